@@ -0,0 +1,79 @@
+// Package admin serves operator endpoints, guarded by the configured
+// AdminToken so they stay invisible to the crawlers in the maze.
+package admin
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"Erebus/internal/bable"
+	"Erebus/internal/erebusconfig"
+)
+
+// StatsPath is where StatsHandler is mounted. It deliberately stays clear
+// of the /admin/ bait advertised in robots.txt.
+const StatsPath = "/_erebus/stats"
+
+const (
+	defaultSamples = 200
+	maxSamples     = 5000
+)
+
+// Enabled reports whether an admin token is configured.
+func Enabled() bool {
+	return erebusconfig.Conf.AdminToken != ""
+}
+
+// authorized reports whether r carries the admin token as a bearer token.
+func authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || !Enabled() {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(erebusconfig.Conf.AdminToken)) == 1
+}
+
+// StatsHandler reports the quality of the shared chains as JSON, one
+// entry per language, or only the one named by ?lang=. ?samples= sets
+// how many sentences are generated to measure verbatim copying.
+// Requests without the token get a plain 404.
+func StatsHandler(w http.ResponseWriter, r *http.Request) {
+	if !authorized(r) {
+		http.NotFound(w, r)
+		return
+	}
+
+	samples := defaultSamples
+	if s := r.URL.Query().Get("samples"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			http.Error(w, "invalid samples", http.StatusBadRequest)
+			return
+		}
+		samples = min(n, maxSamples)
+	}
+
+	langs := bable.Languages()
+	if lang := r.URL.Query().Get("lang"); lang != "" {
+		langs = []string{lang}
+	}
+
+	rng := rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())) //nolint:gosec
+	reports := make([]bable.Report, 0, len(langs))
+	for _, lang := range langs {
+		reports = append(reports, bable.SharedReport(rng, lang, samples))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(reports); err != nil {
+		slog.Error("admin stats encode failed", "err", err)
+	}
+}
@@ -2,21 +2,36 @@
 package bable
 
 import (
-	"bufio"
 	"encoding/binary"
 	"fmt"
+	"iter"
 	"math/rand/v2"
 	"os"
 	"regexp"
 	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
 )
 
-// Chain is a Markov chain text generator.
+// Chain is a variable-order Markov chain text generator.
+// It records transitions for every prefix length from 1 up to maxOrder,
+// so generation can back off to a shorter prefix whenever a longer one
+// is too sparse. Once built, a Chain is only read from and is safe for
+// concurrent use.
 type Chain struct {
-	vocab     []string
-	wordToID  map[string]int
-	chain     map[string][]int
-	prefixLen int
+	vocab    []string
+	wordToID map[string]int
+	// chain is keyed by encoded prefixes of every order; the key length
+	// tells the orders apart.
+	chain    map[string]transitions
+	maxOrder int
+	// predecessors indexes the order-1 transitions backwards for topic
+	// steering. It is built on first use, once the chain is complete.
+	predecessors func() map[int32][]int32
+	// grammar sorts the vocabulary into word classes for headlines. It
+	// is built on first use and is nil when the corpus is too small.
+	grammar func() *grammar
 }
 
 // Prefix represents a word ID prefix used in the Markov chain.
@@ -27,14 +42,22 @@ const (
 	endToken   = "<END>"
 )
 
-// NewChain creates a new Markov chain with the specified prefix length.
-func NewChain(prefixLen int) *Chain {
-	return &Chain{
-		vocab:     make([]string, 0, 1000),
-		wordToID:  make(map[string]int),
-		chain:     make(map[string][]int),
-		prefixLen: prefixLen,
+// NewChain creates a new Markov chain storing prefixes of up to maxOrder words.
+func NewChain(maxOrder int) *Chain {
+	chain := &Chain{
+		vocab:    make([]string, 0, 1000),
+		wordToID: make(map[string]int),
+		chain:    make(map[string]transitions),
+		maxOrder: maxOrder,
 	}
+	chain.predecessors = sync.OnceValue(chain.buildPredecessors)
+	chain.grammar = sync.OnceValue(chain.buildGrammar)
+	return chain
+}
+
+// MaxOrder returns the longest prefix length the chain stores.
+func (chain *Chain) MaxOrder() int {
+	return chain.maxOrder
 }
 
 // internWord converts a word to its unique ID, creating a new ID if needed.
@@ -53,6 +76,7 @@ func (chain *Chain) Build(text string) {
 	sentences := splitIntoSentences(text)
 	startID := chain.internWord(startToken)
 	endID := chain.internWord(endToken)
+	counts := make(map[string]successorCounts)
 
 	for _, sentence := range sentences {
 		words := tokenize(sentence)
@@ -60,31 +84,71 @@ func (chain *Chain) Build(text string) {
 			continue
 		}
 
-		prefix := make(Prefix, chain.prefixLen)
-		for i := range prefix {
-			prefix[i] = startID
-		}
-
+		history := chain.startHistory(startID)
 		for _, word := range words {
 			wordID := chain.internWord(word)
-			key := encodePrefix(prefix)
-			chain.chain[key] = append(chain.chain[key], wordID)
-			prefix.Shift(wordID)
+			chain.addTransitions(counts, history, wordID)
+			history.Shift(wordID)
 		}
 
-		key := encodePrefix(prefix)
-		chain.chain[key] = append(chain.chain[key], endID)
+		chain.addTransitions(counts, history, endID)
 	}
+	chain.commit(counts)
 }
 
-// sentenceRe matches sentence-ending punctuation followed by whitespace and a capital letter.
-// Requiring a capital letter avoids false splits on abbreviations like "i.e." or "Mr.".
-var sentenceRe = regexp.MustCompile(`([.!?]+)\s+([A-Z])`)
+// startHistory returns a maxOrder-long history filled with START tokens.
+func (chain *Chain) startHistory(startID int) Prefix {
+	history := make(Prefix, chain.maxOrder)
+	for i := range history {
+		history[i] = startID
+	}
+	return history
+}
+
+// addTransitions counts one occurrence of wordID following every
+// suffix of history, from order 1 up to maxOrder.
+func (chain *Chain) addTransitions(counts map[string]successorCounts, history Prefix, wordID int) {
+	for order := 1; order <= len(history); order++ {
+		key := encodePrefix(history[len(history)-order:])
+		next := counts[key]
+		if next == nil {
+			next = make(successorCounts)
+			counts[key] = next
+		}
+		next[int32(wordID)]++ //nolint:gosec
+	}
+}
+
+// commit turns the counts gathered by Build into transitions, merging
+// them with whatever an earlier Build recorded for the same prefixes.
+func (chain *Chain) commit(counts map[string]successorCounts) {
+	if len(chain.chain) == 0 {
+		// The usual case of a single Build: nothing to merge, and the
+		// map can be sized once instead of growing.
+		chain.chain = make(map[string]transitions, len(counts))
+		for key, next := range counts {
+			chain.chain[key] = newTransitions(next)
+		}
+		return
+	}
+	for key, next := range counts {
+		if old, ok := chain.chain[key]; ok {
+			old.addTo(next)
+		}
+		chain.chain[key] = newTransitions(next)
+	}
+}
+
+// sentenceRe matches sentence-ending punctuation followed by whitespace and an
+// upper-case letter in any script. Requiring a capital letter avoids false
+// splits on abbreviations like "i.e." or "Mr.". Full-width CJK terminators
+// end a sentence on their own, since those scripts have no spaces or case.
+var sentenceRe = regexp.MustCompile(`([.!?]+)\s+(\p{Lu})|([。！？])`)
 
 func splitIntoSentences(text string) []string {
 	// Insert a null byte between the punctuation and the capital letter so we can
 	// split there without losing the capital that starts the next sentence.
-	separated := sentenceRe.ReplaceAllString(text, "$1\x00$2")
+	separated := sentenceRe.ReplaceAllString(text, "$1$3\x00$2")
 	parts := strings.Split(separated, "\x00")
 
 	sentences := make([]string, 0, len(parts))
@@ -97,12 +161,121 @@ func splitIntoSentences(text string) []string {
 	return sentences
 }
 
-// wordRe matches individual words only (no punctuation in the chain).
-var wordRe = regexp.MustCompile(`[a-zA-Z']+`)
+// tokenRe matches words in any script, keeping their case, combining marks
+// and inner apostrophes or hyphens, and the punctuation marks that survive
+// into the chain. Quotes and brackets are dropped since the chain can't
+// keep them balanced.
+var tokenRe = regexp.MustCompile(`[\p{L}\p{M}]+(?:['’-][\p{L}\p{M}]+)*|[.,;:!?()–。，、！？；：]`)
 
-// tokenize splits text into individual words, ignoring punctuation.
+// tokenize splits text into words and punctuation tokens.
+// Runs of CJK characters are split into single characters, since those
+// scripts don't separate words with spaces.
 func tokenize(text string) []string {
-	return wordRe.FindAllString(text, -1)
+	matches := tokenRe.FindAllString(text, -1)
+	tokens := make([]string, 0, len(matches))
+	for _, match := range matches {
+		if !strings.ContainsFunc(match, isCJK) {
+			tokens = append(tokens, match)
+			continue
+		}
+		for _, r := range match {
+			tokens = append(tokens, string(r))
+		}
+	}
+	return tokens
+}
+
+// isCJK reports whether r belongs to a script written without spaces.
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana)
+}
+
+// isPunct reports whether token is a punctuation token from tokenRe.
+func isPunct(token string) bool {
+	return token != "" && strings.Contains(".,;:!?()–。，、！？；：", token)
+}
+
+// isTerminal reports whether token ends a sentence.
+func isTerminal(token string) bool {
+	switch token {
+	case ".", "!", "?", "。", "！", "？":
+		return true
+	}
+	return false
+}
+
+// unspaced reports whether token is CJK text or punctuation, which is
+// written without a space in front of it.
+func unspaced(token string) bool {
+	r, _ := utf8.DecodeRuneInString(token)
+	return isCJK(r) || (r >= 0x3000 && r <= 0x303f) || (r >= 0xff00 && r <= 0xffef)
+}
+
+// Detokenize renders tokens as prose: punctuation is attached to the
+// preceding word, parentheses are balanced, the first letter is
+// capitalised and the sentence always ends with terminal punctuation.
+func Detokenize(tokens []string) string {
+	var b strings.Builder
+	openParens := 0
+	spaceNext := false
+	lastPunct := false
+	lastUnspaced := false
+
+	for _, token := range tokens {
+		switch token {
+		case "(":
+			if spaceNext {
+				b.WriteByte(' ')
+			}
+			b.WriteString(token)
+			openParens++
+			spaceNext = false
+			continue
+		case ")":
+			if openParens == 0 {
+				continue
+			}
+			openParens--
+		}
+
+		switch {
+		case isPunct(token) && (b.Len() == 0 || lastPunct):
+			// Never start a sentence with punctuation or stack marks
+			// like ",." that dropped numbers or quotes leave behind.
+			if token == ")" {
+				b.WriteString(token)
+			}
+			continue
+		case isPunct(token) && token != "–":
+			b.WriteString(token)
+		default:
+			if spaceNext && !(unspaced(token) && lastUnspaced) {
+				b.WriteByte(' ')
+			}
+			b.WriteString(token)
+		}
+		lastUnspaced = unspaced(token)
+		spaceNext = true
+		lastPunct = isPunct(token) && token != ")"
+	}
+
+	// Drop trailing punctuation that can't end a sentence.
+	text := strings.TrimRight(b.String(), " ,;:–(，、；：")
+	if text == "" {
+		return ""
+	}
+	// Close whatever is still open, counting again since trimming may
+	// have removed a dangling "(".
+	text += strings.Repeat(")", strings.Count(text, "(")-strings.Count(text, ")"))
+	last, _ := utf8.DecodeLastRuneInString(text)
+	switch {
+	case isTerminal(string(last)):
+	case unspaced(string(last)):
+		text += "。"
+	default:
+		text += "."
+	}
+	return capitalise(text)
 }
 
 // encodePrefix encodes word IDs as a binary string key, avoiding hash collisions.
@@ -114,43 +287,104 @@ func encodePrefix(prefix []int) string {
 	return string(buf)
 }
 
-// GenerateSentences produces complete sentences using START/END tokens.
-func (chain *Chain) GenerateSentences(numSentences int) string {
+// GenerateSentences produces complete sentences using START/END tokens,
+// using prefixes of up to the chain's maximum order.
+// All choices are drawn from rng, so the same seed yields the same text.
+func (chain *Chain) GenerateSentences(rng *rand.Rand, numSentences int) string {
+	return chain.Generate(rng, numSentences, chain.maxOrder, DefaultCreativity(), nil)
+}
+
+// Generate produces numSentences sentences using prefixes of at most order
+// words, backing off to shorter prefixes as creativity allows. A non-nil
+// topic steers the word choices toward it.
+func (chain *Chain) Generate(rng *rand.Rand, numSentences, order int,
+	creativity Creativity, topic *Topic) string {
+	order = max(1, min(order, chain.maxOrder))
 	sentences := make([]string, 0, numSentences)
 
 	for range numSentences {
-		sentence := chain.generateOneSentence()
+		sentence := chain.generateOneSentence(rng, order, creativity, topic)
 		if sentence != "" {
 			sentences = append(sentences, sentence)
 		}
 	}
 
-	return strings.Join(sentences, " ")
+	return joinSentences(sentences)
 }
 
-func (chain *Chain) generateOneSentence() string {
+// maxEmptySentences is how many empty sentences in a row end a stream.
+// A chain that keeps producing them has nothing else to say, and looping
+// on it would never hand control back to the consumer.
+const maxEmptySentences = 100
+
+// Stream returns an endless sequence of sentences generated like Generate
+// does. It only ends when the consumer stops, the chain is empty or it
+// stops producing sentences.
+func (chain *Chain) Stream(rng *rand.Rand, order int, creativity Creativity,
+	topic *Topic) iter.Seq[string] {
+	order = max(1, min(order, chain.maxOrder))
+	return func(yield func(string) bool) {
+		startID, ok := chain.wordToID[startToken]
+		if !ok {
+			return
+		}
+		// A chain built from an empty corpus has START but nothing after it.
+		if first := chain.chain[encodePrefix([]int{startID})]; first.total() == 0 {
+			return
+		}
+		for empty := 0; empty < maxEmptySentences; {
+			sentence := chain.generateOneSentence(rng, order, creativity, topic)
+			if sentence == "" {
+				empty++
+				continue
+			}
+			empty = 0
+			if !yield(sentence) {
+				return
+			}
+		}
+	}
+}
+
+// joinSentences separates sentences with a space, except after CJK
+// sentences which run on without one.
+func joinSentences(sentences []string) string {
+	var b strings.Builder
+	for i, sentence := range sentences {
+		if i > 0 {
+			last, _ := utf8.DecodeLastRuneInString(sentences[i-1])
+			if !unspaced(string(last)) {
+				b.WriteByte(' ')
+			}
+		}
+		b.WriteString(sentence)
+	}
+	return b.String()
+}
+
+func (chain *Chain) generateOneSentence(rng *rand.Rand, order int,
+	creativity Creativity, topic *Topic) string {
 	startID, hasStart := chain.wordToID[startToken]
 	if !hasStart {
 		return ""
 	}
 
-	prefix := make(Prefix, chain.prefixLen)
-	for i := range prefix {
-		prefix[i] = startID
-	}
-
+	history := chain.startHistory(startID)
 	tokens := make([]string, 0, 20)
 	const maxTokens = 100
 
 	for len(tokens) < maxTokens {
-		key := encodePrefix(prefix)
-		choices := chain.chain[key]
-
-		if len(choices) == 0 {
+		choices, ok := chain.successors(rng, history, order, creativity)
+		if !ok {
 			break
 		}
 
-		nextID := choices[rand.IntN(len(choices))] //nolint:gosec
+		var nextID int
+		if topic != nil && creativity.Focus > 1 {
+			nextID = choices.sampleToward(rng, topic, creativity.Focus)
+		} else {
+			nextID = choices.sample(rng)
+		}
 		nextToken := chain.vocab[nextID]
 
 		if nextToken == endToken || nextToken == startToken {
@@ -158,10 +392,37 @@ func (chain *Chain) generateOneSentence() string {
 		}
 
 		tokens = append(tokens, nextToken)
-		prefix.Shift(nextID)
+		history.Shift(nextID)
+	}
+
+	return Detokenize(tokens)
+}
+
+// successors picks the transitions to sample the next word from. It starts
+// at the longest allowed prefix, occasionally jumping one order lower, and
+// backs off while a prefix has fewer than creativity.MinSuccessors distinct
+// successors. If no prefix is branchy enough the longest one found is used.
+func (chain *Chain) successors(rng *rand.Rand, history Prefix, order int,
+	creativity Creativity) (transitions, bool) {
+	if order > 1 && creativity.Jump > 0 && rng.Float64() < creativity.Jump {
+		order--
 	}
 
-	return strings.Join(tokens, " ")
+	var fallback transitions
+	found := false
+	for k := order; k >= 1; k-- {
+		next, ok := chain.chain[encodePrefix(history[len(history)-k:])]
+		if !ok || next.total() == 0 {
+			continue
+		}
+		if len(next.ids) >= creativity.MinSuccessors {
+			return next, true
+		}
+		if !found {
+			fallback, found = next, true
+		}
+	}
+	return fallback, found
 }
 
 // Shift removes the first element and appends wordID to the end.
@@ -171,23 +432,22 @@ func (prefix Prefix) Shift(wordID int) {
 }
 
 // ReadManifesto reads the manifest file and returns its contents as a single string.
-func ReadManifesto() string {
-	file, err := os.Open("manifest")
+func ReadManifesto() (string, error) {
+	return ReadCorpus("manifest")
+}
+
+// ReadCorpus reads a plain-text corpus file and returns its non-empty
+// lines joined into a single string. The whole file is read at once, so
+// lines of any length are kept.
+func ReadCorpus(path string) (string, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // path comes from the config
 	if err != nil {
-		fmt.Printf("error opening file: %s\n", err)
-		return ""
+		return "", fmt.Errorf("read corpus: %w", err)
 	}
-	defer func() {
-		if err := file.Close(); err != nil {
-			fmt.Printf("error closing file: %s\n", err)
-		}
-	}()
 
 	var sb strings.Builder
-	scanner := bufio.NewScanner(file)
-
-	for scanner.Scan() {
-		line := scanner.Text()
+	for line := range strings.Lines(string(data)) {
+		line = strings.TrimRight(line, "\r\n")
 		if line != "" {
 			if sb.Len() > 0 {
 				sb.WriteByte(' ')
@@ -195,17 +455,5 @@ func ReadManifesto() string {
 			sb.WriteString(line)
 		}
 	}
-
-	if err := scanner.Err(); err != nil {
-		fmt.Printf("error reading file: %s\n", err)
-	}
-
-	return sb.String()
-}
-
-// Bable generates random text by building a Markov chain from the manifesto.
-func Bable(numSentences int, prefixLen int) string {
-	chain := NewChain(prefixLen)
-	chain.Build(ReadManifesto())
-	return chain.GenerateSentences(numSentences)
+	return sb.String(), nil
 }
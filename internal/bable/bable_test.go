@@ -0,0 +1,105 @@
+package bable
+
+import (
+	"math/rand/v2"
+	"testing"
+	"time"
+)
+
+// repoRoot moves the test into the repository root, where the corpora
+// live, with a fresh set of chains.
+func repoRoot(tb testing.TB) {
+	tb.Helper()
+	tb.Chdir("../..")
+	current.Store(newLibrary())
+}
+
+// BenchmarkBuild measures tokenizing the manifest into a chain, which
+// every Bable call paid for before the chains were shared.
+func BenchmarkBuild(b *testing.B) {
+	repoRoot(b)
+	text, err := ReadCorpus(corpora()[DefaultLanguage])
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	for b.Loop() {
+		NewChain(maxOrder()).Build(text)
+	}
+}
+
+// BenchmarkGenerators measures each backend on the shared chains.
+func BenchmarkGenerators(b *testing.B) {
+	repoRoot(b)
+	Warm()
+	for _, name := range GeneratorNames() {
+		gen, err := GeneratorFor(name, DefaultLanguage)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(name, func(b *testing.B) {
+			rng := rand.New(rand.NewPCG(1, 2)) //nolint:gosec
+			b.ReportAllocs()
+			for b.Loop() {
+				gen.Sentences(rng, 5)
+				gen.Title(rng, 8)
+				gen.Slug(rng, 3)
+			}
+		})
+	}
+}
+
+// TestStreamEmptyChain checks that a chain built from an empty corpus
+// ends its stream instead of looping on empty sentences.
+func TestStreamEmptyChain(t *testing.T) {
+	chain := NewChain(3)
+	chain.Build("")
+
+	done := make(chan int)
+	go func() {
+		n := 0
+		for range chain.Stream(rand.New(rand.NewPCG(1, 2)), 3, DefaultCreativity(), nil) { //nolint:gosec
+			n++
+		}
+		done <- n
+	}()
+	select {
+	case n := <-done:
+		if n != 0 {
+			t.Errorf("empty chain streamed %d sentences", n)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream of an empty chain did not end")
+	}
+}
+
+func TestDetokenize(t *testing.T) {
+	tests := []struct {
+		name   string
+		tokens []string
+		want   string
+	}{
+		{"empty", nil, ""},
+		{"adds full stop", []string{"the", "workers", "unite"}, "The workers unite."},
+		{"keeps terminal", []string{"why", "not", "?"}, "Why not?"},
+		{"attaches punctuation", []string{"bread", ",", "peace", "and", "land", "!"}, "Bread, peace and land!"},
+		{"spaces dash", []string{"one", "–", "two"}, "One – two."},
+		{"drops leading punctuation", []string{",", ".", "history", "moves"}, "History moves."},
+		{"drops stacked punctuation", []string{"capital", ",", ".", "labour"}, "Capital, labour."},
+		{"trims trailing comma", []string{"the", "end", ","}, "The end."},
+		{"parentheses", []string{"a", "(", "b", "c", ")", "d"}, "A (b c) d."},
+		{"closes open parenthesis", []string{"a", "(", "b", "c"}, "A (b c)."},
+		{"drops unmatched close", []string{"a", ")", "b"}, "A b."},
+		{"drops dangling open", []string{"a", "b", "("}, "A b."},
+		{"cjk unspaced", []string{"共", "产", "党", "，", "宣", "言"}, "共产党，宣言。"},
+		{"cjk keeps terminal", []string{"宣", "言", "！"}, "宣言！"},
+		{"cyrillic", []string{"пролетарии", "всех", "стран", ",", "соединяйтесь", "!"}, "Пролетарии всех стран, соединяйтесь!"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Detokenize(tt.tokens); got != tt.want {
+				t.Errorf("Detokenize(%q) = %q, want %q", tt.tokens, got, tt.want)
+			}
+		})
+	}
+}
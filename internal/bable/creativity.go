@@ -0,0 +1,23 @@
+package bable
+
+import "Erebus/internal/erebusconfig"
+
+// Creativity tunes how far generated text strays from the corpus. It is
+// the config type itself, so the settings are documented in one place.
+type Creativity = erebusconfig.Creativity
+
+// defaultFocus is the topic focus used when none is configured.
+const defaultFocus = 6
+
+// DefaultCreativity returns the configured creativity settings,
+// requiring two successors, never jumping and using defaultFocus when unset.
+func DefaultCreativity() Creativity {
+	c := erebusconfig.Conf.Creativity
+	if c.MinSuccessors <= 0 {
+		c.MinSuccessors = 2
+	}
+	if c.Focus == 0 {
+		c.Focus = defaultFocus
+	}
+	return c
+}
@@ -0,0 +1,208 @@
+package bable
+
+import (
+	"fmt"
+	"iter"
+	"math/rand/v2"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Generator produces the filler text used to build tarpit pages.
+// Implementations must be safe for concurrent use and must draw all of
+// their randomness from the rng they are given, so that a seeded rng
+// always reproduces the same text.
+type Generator interface {
+	// Sentences returns numSentences sentences of running text.
+	Sentences(rng *rand.Rand, numSentences int) string
+	// Title returns a headline of at most maxWords words.
+	Title(rng *rand.Rand, maxWords int) string
+	// Slug returns a URL-friendly slug of up to wordCount words.
+	Slug(rng *rand.Rand, wordCount int) string
+	// ListItem returns a single bullet-point line.
+	ListItem(rng *rand.Rand) string
+	// Stream returns an endless sequence of sentences, for bodies that
+	// are streamed until the client or a budget gives up.
+	Stream(rng *rand.Rand) iter.Seq[string]
+	// About returns a generator whose text leans toward the seed words,
+	// such as those in the requested URL.
+	About(seeds []string) Generator
+}
+
+// DefaultGenerator is the backend used when none is configured.
+const DefaultGenerator = "markov"
+
+// generators holds a constructor for every selectable backend by its
+// config name. Backends without per-language data ignore lang.
+var generators = map[string]func(lang string) Generator{
+	"markov":    func(lang string) Generator { return NewMarkov(lang) },
+	"wordsalad": func(string) Generator { return WordSalad{} },
+}
+
+// GeneratorFor returns the backend registered under name, producing text
+// in lang where the backend supports it. An empty name selects
+// DefaultGenerator.
+func GeneratorFor(name, lang string) (Generator, error) {
+	if name == "" {
+		name = DefaultGenerator
+	}
+	newGen, ok := generators[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown generator %q (available: %s)",
+			name, strings.Join(GeneratorNames(), ", "))
+	}
+	return newGen(lang), nil
+}
+
+// GeneratorNames lists the registered backends in sorted order.
+func GeneratorNames() []string {
+	names := make([]string, 0, len(generators))
+	for name := range generators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Markov generates text from the shared Markov chain built from the
+// corpus for Lang.
+type Markov struct {
+	Lang string
+	// lib pins the chains in use when the generator was created, so a
+	// reload never switches a page to different chains halfway through.
+	lib *library
+	// topic steers generation when set through About.
+	topic *Topic
+}
+
+// NewMarkov returns a Markov generator for lang bound to the current chains.
+func NewMarkov(lang string) Markov {
+	return Markov{Lang: lang, lib: current.Load()}
+}
+
+// chain returns the pinned chain for m.Lang, or the current one for a
+// zero Markov.
+func (m Markov) chain() *Chain {
+	if m.lib == nil {
+		return Shared(m.Lang)
+	}
+	return m.lib.chain(m.Lang)
+}
+
+// lang returns the language m actually generates, after falling back
+// from a language without a corpus.
+func (m Markov) lang() string {
+	if _, ok := corpora()[m.Lang]; !ok {
+		return DefaultLanguage
+	}
+	return m.Lang
+}
+
+func (m Markov) bable(rng *rand.Rand, numSentences int, order int) string {
+	return m.chain().Generate(rng, numSentences, order, DefaultCreativity(), m.topic)
+}
+
+// Sentences returns running text using the chain's full order.
+func (m Markov) Sentences(rng *rand.Rand, numSentences int) string {
+	return m.bable(rng, numSentences, maxOrder())
+}
+
+// Title returns a headline filled in from the corpus vocabulary, leaning
+// toward the topic. Corpora without a grammar get the opening words of
+// an order-1 sentence instead.
+func (m Markov) Title(rng *rand.Rand, maxWords int) string {
+	chain := m.chain()
+	if g := chain.grammar(); g != nil && grammarLangs[m.lang()] {
+		if title := g.headline(rng, maxWords, m.topic.words(chain)); title != "" {
+			return title
+		}
+	}
+	return firstWords(m.bable(rng, 1, 1), maxWords)
+}
+
+// Slug returns a slug built from an order-1 sentence.
+func (m Markov) Slug(rng *rand.Rand, wordCount int) string {
+	return slugify(strings.Fields(m.bable(rng, 1, 1)), wordCount)
+}
+
+// ListItem returns a single sentence of at most order 2.
+func (m Markov) ListItem(rng *rand.Rand) string {
+	return m.bable(rng, 1, 2)
+}
+
+// About returns a copy of m steered toward the seeds found in its chain.
+func (m Markov) About(seeds []string) Generator {
+	m.topic = m.chain().Topic(seeds)
+	return m
+}
+
+// Stream returns endless running text using the chain's full order.
+func (m Markov) Stream(rng *rand.Rand) iter.Seq[string] {
+	return m.chain().Stream(rng, maxOrder(), DefaultCreativity(), m.topic)
+}
+
+var stopWords = map[string]bool{
+	"the": true, "a": true, "an": true, "and": true, "or": true,
+	"of": true, "in": true, "to": true, "for": true, "is": true,
+	"it": true, "by": true, "on": true, "at": true, "as": true,
+	"its": true, "was": true, "are": true, "be": true, "has": true,
+	"had": true, "have": true, "with": true, "from": true, "this": true,
+	"that": true, "which": true, "but": true, "not": true, "all": true,
+}
+
+// IsStopWord reports whether the lowercase word is too common to carry meaning.
+func IsStopWord(word string) bool {
+	return stopWords[word]
+}
+
+// firstWords trims text to at most maxWords words, dropping any punctuation
+// left dangling at the end. CJK text has no spaces, so it is trimmed to
+// two characters per word instead.
+func firstWords(text string, maxWords int) string {
+	const trailing = ".,;:!?–(。，、！？；："
+	if strings.ContainsFunc(text, isCJK) {
+		runes := []rune(text)
+		if len(runes) > maxWords*2 {
+			runes = runes[:maxWords*2]
+		}
+		return strings.TrimRight(string(runes), trailing)
+	}
+
+	words := strings.Fields(text)
+	if len(words) > maxWords {
+		words = words[:maxWords]
+	}
+	return strings.TrimRight(strings.Join(words, " "), trailing)
+}
+
+// slugify joins up to wordCount meaningful words with hyphens.
+func slugify(words []string, wordCount int) string {
+	var kept []string
+	for _, w := range words {
+		cleaned := lowerLetters(w)
+		if cleaned == "" || stopWords[cleaned] {
+			continue
+		}
+		kept = append(kept, cleaned)
+		if len(kept) >= wordCount {
+			break
+		}
+	}
+
+	if len(kept) == 0 {
+		return "page"
+	}
+	return strings.Join(kept, "-")
+}
+
+// lowerLetters drops everything but letters and lowercases the rest.
+func lowerLetters(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if unicode.IsLetter(r) {
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,265 @@
+package bable
+
+import (
+	"math/rand/v2"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// grammarLangs lists the languages the headline templates and suffix
+// heuristics are written for. Other languages keep Markov titles.
+var grammarLangs = map[string]bool{"en": true}
+
+// Word classes a headline template can ask for.
+const (
+	classNoun   = "Noun"   // singular noun
+	classNouns  = "Nouns"  // plural noun
+	classAdj    = "Adj"    // adjective
+	classPast   = "Past"   // past-tense verb
+	classGerund = "Gerund" // -ing form
+)
+
+// headlineTemplates are filled in to make titles and headings. Slots in
+// braces name a word class, or {Year} and {Number}; anything after the
+// closing brace, such as a colon, is kept.
+var headlineTemplates = []string{
+	"The {Adj} {Noun}",
+	"{Adj} {Nouns}",
+	"Beyond the {Noun}",
+	"{Gerund} {Nouns}",
+	"Notes on {Gerund}",
+	"{Nouns} and {Nouns}",
+	"Why {Nouns} matter",
+	"The {Noun} after {Year}",
+	"How the {Noun} {Past}",
+	"The {Noun} of {Nouns}",
+	"{Adj} {Nouns} in {Year}",
+	"{Nouns} and the {Adj} {Noun}",
+	"On the {Adj} {Noun} of {Nouns}",
+	"How {Nouns} {Past} the {Noun}",
+	"Why the {Noun} {Past} {Nouns}",
+	"A short history of {Nouns}",
+	"A {Adj} guide to {Gerund} {Nouns}",
+	"{Gerund} the {Noun}: a {Adj} history",
+	"After the {Noun}: {Nouns} since {Year}",
+	"What {Gerund} {Nouns} means for the {Noun}",
+	"{Number} {Adj} {Nouns} that {Past} the {Noun}",
+}
+
+// Suffix heuristics sorting the vocabulary into word classes. They are
+// crude, but headlines only need the words to look like they fit.
+var (
+	adjSuffixes  = []string{"ous", "ful", "ive", "able", "ible", "ical", "al", "ic", "ary", "less", "ish"}
+	nounSuffixes = []string{
+		"tion", "sion", "ment", "ness", "ity", "ism", "ist", "ship", "ance", "ence", "dom", "hood",
+	}
+	notNounEndings = []string{"ly", "ed", "ing", "est"}
+	// unclassified are common words the endings and determiners get wrong.
+	unclassified = map[string]bool{
+		"during": true, "nothing": true, "something": true, "anything": true,
+		"everything": true, "morning": true, "evening": true, "ceiling": true,
+		"indeed": true, "hundred": true, "hitherto": true, "cannot": true,
+		"without": true, "first": true, "former": true, "latter": true,
+		"whole": true, "single": true, "other": true, "same": true, "very": true,
+	}
+)
+
+// determiners introduce a noun phrase. Words following one are taken to
+// be nouns or adjectives, and -ed and -ing forms followed by one to be
+// verbs taking an object.
+var determiners = map[string]bool{
+	"the": true, "a": true, "an": true, "this": true,
+	"these": true, "those": true, "his": true, "her": true, "its": true,
+	"their": true, "our": true, "every": true, "many": true,
+}
+
+// minClassWords is how many words every class needs before the corpus
+// is trusted to fill headlines.
+const minClassWords = 5
+
+// grammar holds the corpus vocabulary sorted into word classes.
+type grammar struct {
+	classes map[string][]string
+	// classOf maps each classified word back to its class.
+	classOf map[string]string
+}
+
+// buildGrammar sorts the lowercase words that occur at least twice into
+// word classes, using their endings and the determiners around them in
+// the order-1 transitions. It returns nil when a class stays too small
+// to be useful.
+func (chain *Chain) buildGrammar() *grammar {
+	isDeterminer := make(map[int32]bool)
+	afterDeterminer := make(map[int32]bool)
+	for id, word := range chain.vocab {
+		if !determiners[strings.ToLower(word)] {
+			continue
+		}
+		isDeterminer[int32(id)] = true //nolint:gosec
+		for _, next := range chain.chain[encodePrefix(Prefix{id})].ids {
+			afterDeterminer[next] = true
+		}
+	}
+
+	g := &grammar{classes: make(map[string][]string), classOf: make(map[string]string)}
+	for id, word := range chain.vocab {
+		if len(word) < 4 || !isLowerAlpha(word) || IsStopWord(word) || determiners[word] {
+			continue
+		}
+		next := chain.chain[encodePrefix(Prefix{id})]
+		if next.total() < 2 {
+			continue
+		}
+		class := classify(word)
+		switch class {
+		case classPast, classGerund:
+			if !slices.ContainsFunc(next.ids, func(id int32) bool { return isDeterminer[id] }) {
+				continue
+			}
+		case "":
+			continue
+		default:
+			if !afterDeterminer[int32(id)] { //nolint:gosec
+				continue
+			}
+		}
+		g.classes[class] = append(g.classes[class], word)
+		g.classOf[word] = class
+	}
+	for _, class := range []string{classNoun, classNouns, classAdj, classPast, classGerund} {
+		if len(g.classes[class]) < minClassWords {
+			return nil
+		}
+	}
+	return g
+}
+
+// classify guesses the word class of a lowercase word from its ending.
+func classify(word string) string {
+	switch {
+	case unclassified[word]:
+		return ""
+	case strings.HasSuffix(word, "ing"):
+		if len(word) >= 6 {
+			return classGerund
+		}
+		return ""
+	case strings.HasSuffix(word, "ed"):
+		if len(word) >= 6 {
+			return classPast
+		}
+		return ""
+	}
+	for _, suffix := range nounSuffixes {
+		if strings.HasSuffix(word, suffix) {
+			return classNoun
+		}
+	}
+	for _, suffix := range adjSuffixes {
+		if strings.HasSuffix(word, suffix) {
+			return classAdj
+		}
+	}
+	for _, ending := range notNounEndings {
+		if strings.HasSuffix(word, ending) {
+			return ""
+		}
+	}
+	if len(word) < 5 {
+		return ""
+	}
+	if strings.HasSuffix(word, "s") {
+		singular := strings.TrimSuffix(word, "s")
+		if strings.HasSuffix(singular, "s") || strings.HasSuffix(singular, "u") || strings.HasSuffix(singular, "i") {
+			return ""
+		}
+		return classNouns
+	}
+	return classNoun
+}
+
+// isLowerAlpha reports whether word consists of lowercase ASCII letters.
+func isLowerAlpha(word string) bool {
+	for _, r := range word {
+		if r < 'a' || r > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+// headline fills a random template of at most maxWords words. Words from
+// topic are preferred for the slots they fit. It returns "" when no
+// template is short enough.
+func (g *grammar) headline(rng *rand.Rand, maxWords int, topic []string) string {
+	var fitting []string
+	for _, tmpl := range headlineTemplates {
+		if len(strings.Fields(tmpl)) <= maxWords {
+			fitting = append(fitting, tmpl)
+		}
+	}
+	if len(fitting) == 0 {
+		return ""
+	}
+
+	words := strings.Fields(fitting[rng.IntN(len(fitting))])
+	for i, word := range words {
+		if !strings.HasPrefix(word, "{") {
+			continue
+		}
+		end := strings.IndexByte(word, '}')
+		words[i] = g.fill(rng, word[1:end], topic) + word[end+1:]
+	}
+	fixArticles(words)
+	return capitalise(strings.Join(words, " "))
+}
+
+// fill returns a word for slot, taking one of the topic words of the
+// right class half of the time.
+func (g *grammar) fill(rng *rand.Rand, slot string, topic []string) string {
+	switch slot {
+	case "Year":
+		return strconv.Itoa(1840 + rng.IntN(186))
+	case "Number":
+		return strconv.Itoa(3 + rng.IntN(10))
+	}
+	if rng.IntN(2) == 0 {
+		var matching []string
+		for _, word := range topic {
+			if g.classOf[word] == slot {
+				matching = append(matching, word)
+			}
+		}
+		if len(matching) > 0 {
+			return matching[rng.IntN(len(matching))]
+		}
+	}
+	class := g.classes[slot]
+	return class[rng.IntN(len(class))]
+}
+
+// fixArticles turns "a" into "an" in front of words starting with a vowel.
+func fixArticles(words []string) {
+	for i := range len(words) - 1 {
+		if words[i] == "a" || words[i] == "A" {
+			if strings.ContainsRune("aeiouAEIOU", rune(words[i+1][0])) {
+				words[i] += "n"
+			}
+		}
+	}
+}
+
+// words returns the lowercase words of the topic in sorted order.
+func (topic *Topic) words(chain *Chain) []string {
+	if topic == nil {
+		return nil
+	}
+	words := make([]string, 0, len(topic.targets))
+	for id := range topic.targets {
+		words = append(words, strings.ToLower(chain.vocab[id]))
+	}
+	sort.Strings(words)
+	return words
+}
@@ -0,0 +1,104 @@
+package bable
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"Erebus/internal/erebusconfig"
+)
+
+// reloadMu keeps reloads from overlapping.
+var reloadMu sync.Mutex
+
+// Reload rebuilds every chain that is currently loaded from the corpora or
+// snapshots on disk and then swaps them in atomically. Requests that
+// started before the swap finish on the chains they began with. If any
+// chain comes out empty, for example because a corpus file is missing or
+// being rewritten, the current chains are kept and an error is returned.
+func Reload() error {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	start := time.Now()
+	langs := current.Load().langs()
+
+	next := newLibrary()
+	for _, lang := range langs {
+		chain := next.chain(lang)
+		st := chain.Stats()
+		if st.Prefixes == 0 {
+			return fmt.Errorf("reload %s chain: %w", lang, errEmptyChain)
+		}
+		slog.Info("chain rebuilt",
+			"lang", lang,
+			"max_order", chain.MaxOrder(),
+			"corpus", corpora()[lang],
+			"vocab", st.Vocab,
+			"prefixes", st.Prefixes,
+			"transitions", st.Transitions,
+		)
+	}
+
+	current.Store(next)
+	slog.Info("corpora reloaded", "chains", len(langs), "took", time.Since(start))
+	return nil
+}
+
+var errEmptyChain = errors.New("chain is empty, keeping the previous one")
+
+// Watch polls the corpus files, and the snapshots when SnapshotDir is set,
+// every interval and reloads the chains when any of them changes.
+// It returns when ctx is cancelled.
+func Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last := watchedFiles()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			seen := watchedFiles()
+			if seen == last {
+				continue
+			}
+			last = seen
+			slog.Info("corpus files changed, reloading")
+			if err := Reload(); err != nil {
+				slog.Error("corpus reload failed", "error", err)
+			}
+		}
+	}
+}
+
+// watchedFiles returns a fingerprint of the size and modification time of
+// every file the loaded chains are built from.
+func watchedFiles() string {
+	var paths []string
+	for _, path := range corpora() {
+		paths = append(paths, path)
+	}
+	if dir := erebusconfig.Conf.SnapshotDir; dir != "" {
+		for _, lang := range current.Load().langs() {
+			paths = append(paths, SnapshotPath(dir, lang))
+		}
+	}
+	sort.Strings(paths)
+
+	fingerprint := make([]byte, 0, len(paths)*64)
+	for _, path := range paths {
+		fingerprint = append(fingerprint, path...)
+		if info, err := os.Stat(path); err == nil {
+			fingerprint = fmt.Appendf(fingerprint, ":%d:%d", info.Size(), info.ModTime().UnixNano())
+		}
+		fingerprint = append(fingerprint, '\n')
+	}
+	return string(fingerprint)
+}
@@ -0,0 +1,153 @@
+package bable
+
+import (
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"Erebus/internal/erebusconfig"
+)
+
+// DefaultLanguage is served when no configured corpus matches a request.
+const DefaultLanguage = "en"
+
+// DefaultMaxOrder is the longest prefix stored when MaxOrder is unset.
+const DefaultMaxOrder = 5
+
+// library holds one long-lived chain per language, all built from the
+// same version of the corpora, so each corpus is only tokenized once per
+// process instead of once per call. Chains are added lazily but never
+// replaced; a reload builds a whole new library instead.
+type library struct {
+	mu     sync.RWMutex
+	byLang map[string]*Chain
+}
+
+func newLibrary() *library {
+	return &library{byLang: make(map[string]*Chain)}
+}
+
+// current is the library new requests generate from. It is swapped
+// atomically on reload, while requests that already hold the previous
+// library keep using it until they finish.
+var current atomic.Pointer[library]
+
+func init() {
+	current.Store(newLibrary())
+}
+
+// corpora returns the configured corpus file for each language,
+// defaulting to the English manifest.
+func corpora() map[string]string {
+	if len(erebusconfig.Conf.Corpora) == 0 {
+		return map[string]string{DefaultLanguage: "manifest"}
+	}
+	return erebusconfig.Conf.Corpora
+}
+
+// Languages lists the languages with a configured corpus in sorted order.
+func Languages() []string {
+	langs := make([]string, 0, len(corpora()))
+	for lang := range corpora() {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Shared returns the process-wide chain for lang, loading or building it
+// on first use. Unknown languages fall back to DefaultLanguage.
+func Shared(lang string) *Chain {
+	return current.Load().chain(lang)
+}
+
+func (lib *library) chain(lang string) *Chain {
+	if _, ok := corpora()[lang]; !ok {
+		lang = DefaultLanguage
+	}
+
+	lib.mu.RLock()
+	chain, ok := lib.byLang[lang]
+	lib.mu.RUnlock()
+	if ok {
+		return chain
+	}
+
+	lib.mu.Lock()
+	defer lib.mu.Unlock()
+	// Another caller may have built it while we waited for the lock.
+	if chain, ok := lib.byLang[lang]; ok {
+		return chain
+	}
+	chain = loadOrBuild(lang)
+	lib.byLang[lang] = chain
+	return chain
+}
+
+// langs lists the languages the library has loaded so far, sorted.
+func (lib *library) langs() []string {
+	lib.mu.RLock()
+	defer lib.mu.RUnlock()
+	langs := make([]string, 0, len(lib.byLang))
+	for lang := range lib.byLang {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// maxOrder returns the configured maximum chain order.
+func maxOrder() int {
+	if erebusconfig.Conf.MaxOrder > 0 {
+		return erebusconfig.Conf.MaxOrder
+	}
+	return DefaultMaxOrder
+}
+
+// loadOrBuild loads the snapshot for lang from the configured SnapshotDir,
+// falling back to building the chain from the language's corpus when there
+// is no usable snapshot.
+func loadOrBuild(lang string) *Chain {
+	if dir := erebusconfig.Conf.SnapshotDir; dir != "" {
+		path := SnapshotPath(dir, lang)
+		chain, err := LoadFile(path)
+		if err == nil {
+			return chain
+		}
+		slog.Warn("chain snapshot unusable, building from corpus",
+			"path", path, "error", err)
+	}
+
+	// A missing corpus leaves an empty chain, which generates no text
+	// rather than taking the server down.
+	text, err := ReadCorpus(corpora()[lang])
+	if err != nil {
+		slog.Error("corpus unreadable, chain is empty", "lang", lang, "error", err)
+	}
+	chain := NewChain(maxOrder())
+	chain.Build(text)
+	return chain
+}
+
+// SnapshotPath returns where the snapshot for lang lives inside dir.
+func SnapshotPath(dir, lang string) string {
+	return filepath.Join(dir, fmt.Sprintf("chain-%s.snap", lang))
+}
+
+// Warm builds the shared chain of every language up front,
+// so the first requests don't pay for tokenizing corpora.
+func Warm() {
+	for _, lang := range Languages() {
+		Shared(lang)
+	}
+}
+
+// Bable generates random text from the shared chain for lang, using
+// prefixes of at most order words and the configured creativity.
+func Bable(rng *rand.Rand, lang string, numSentences int, order int) string {
+	return Shared(lang).Generate(rng, numSentences, order, DefaultCreativity(), nil)
+}
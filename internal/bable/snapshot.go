@@ -0,0 +1,333 @@
+package bable
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+	"sort"
+)
+
+// Snapshot layout, all integers little-endian or unsigned varints:
+//
+//	magic     [8]byte  "EREBUSMC"
+//	version   uint32
+//	maxOrder  uvarint
+//	vocab     uvarint count, then per word: uvarint length + bytes
+//	chain     uvarint count, then per entry:
+//	            uvarint order + that many uvarint word IDs,
+//	            uvarint distinct successor count, then per successor:
+//	              uvarint word ID + uvarint occurrence count
+//	checksum  uint32   CRC-32 (IEEE) of everything before it
+//
+// Versions 1 and 2 held a single order, so entries carried no order field
+// and always had maxOrder word IDs. Version 1 also stored one word ID per
+// occurrence instead of ID/count pairs. Both are still accepted by Load.
+const (
+	snapshotMagic   = "EREBUSMC"
+	snapshotVersion = 3
+	// maxSnapshotOrder bounds the prefix length accepted from a file.
+	maxSnapshotOrder = 16
+)
+
+var (
+	// ErrSnapshotMagic indicates the input is not a chain snapshot.
+	ErrSnapshotMagic = errors.New("not a chain snapshot")
+	// ErrSnapshotVersion indicates a snapshot written by an unsupported version.
+	ErrSnapshotVersion = errors.New("unsupported snapshot version")
+	// ErrSnapshotChecksum indicates the snapshot is truncated or corrupted.
+	ErrSnapshotChecksum = errors.New("snapshot checksum mismatch")
+)
+
+// Save writes the chain to w in the versioned snapshot format.
+// Entries are written in key order so equal chains produce equal files.
+func (chain *Chain) Save(w io.Writer) error {
+	crc := crc32.NewIEEE()
+	bw := bufio.NewWriter(io.MultiWriter(w, crc))
+	enc := snapshotWriter{w: bw}
+
+	enc.bytes([]byte(snapshotMagic))
+	enc.uint32(snapshotVersion)
+	enc.uvarint(uint64(chain.maxOrder)) //nolint:gosec
+
+	enc.uvarint(uint64(len(chain.vocab)))
+	for _, word := range chain.vocab {
+		enc.uvarint(uint64(len(word)))
+		enc.bytes([]byte(word))
+	}
+
+	keys := make([]string, 0, len(chain.chain))
+	for key := range chain.chain {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	enc.uvarint(uint64(len(keys)))
+	for _, key := range keys {
+		prefix := decodePrefix(key)
+		enc.uvarint(uint64(len(prefix)))
+		for _, id := range prefix {
+			enc.uvarint(uint64(id)) //nolint:gosec
+		}
+		next := chain.chain[key]
+		enc.uvarint(uint64(len(next.ids)))
+		for i, id := range next.ids {
+			enc.uvarint(uint64(id)) //nolint:gosec
+			enc.uvarint(uint64(next.count(i)))
+		}
+	}
+	if enc.err != nil {
+		return fmt.Errorf("write snapshot: %w", enc.err)
+	}
+
+	// Flush so the checksum covers every byte written so far.
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("write snapshot: %w", err)
+	}
+	var sum [4]byte
+	binary.LittleEndian.PutUint32(sum[:], crc.Sum32())
+	if _, err := w.Write(sum[:]); err != nil {
+		return fmt.Errorf("write snapshot checksum: %w", err)
+	}
+	return nil
+}
+
+// Load reads a chain previously written by Save.
+// The whole snapshot is checksummed before the chain is returned.
+func Load(r io.Reader) (*Chain, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("read snapshot: %w", err)
+	}
+	if len(data) < len(snapshotMagic)+4+4 {
+		return nil, ErrSnapshotChecksum
+	}
+	if !bytes.Equal(data[:len(snapshotMagic)], []byte(snapshotMagic)) {
+		return nil, ErrSnapshotMagic
+	}
+
+	body, trailer := data[:len(data)-4], data[len(data)-4:]
+	if crc32.ChecksumIEEE(body) != binary.LittleEndian.Uint32(trailer) {
+		return nil, ErrSnapshotChecksum
+	}
+
+	dec := snapshotReader{buf: body[len(snapshotMagic):]}
+	version := dec.uint32()
+	if version < 1 || version > snapshotVersion {
+		return nil, fmt.Errorf("%w: %d", ErrSnapshotVersion, version)
+	}
+
+	maxOrder := dec.uvarint()
+	if maxOrder == 0 || maxOrder > maxSnapshotOrder {
+		return nil, fmt.Errorf("decode snapshot: invalid max order %d", maxOrder)
+	}
+	chain := NewChain(int(maxOrder)) //nolint:gosec
+	vocabLen := dec.count()
+	for range vocabLen {
+		chain.internWord(string(dec.bytes(dec.count())))
+	}
+
+	entries := dec.count()
+	// Sized up front, since growing the map dominates loading otherwise.
+	chain.chain = make(map[string]transitions, entries)
+	for range entries {
+		order := chain.maxOrder
+		if version >= 3 {
+			order = int(dec.uvarint()) //nolint:gosec
+			if order < 1 || order > chain.maxOrder {
+				return nil, fmt.Errorf("decode snapshot: invalid order %d", order)
+			}
+		}
+		prefix := make(Prefix, order)
+		for i := range prefix {
+			prefix[i] = dec.wordID(vocabLen)
+		}
+		var next transitions
+		if version == 1 {
+			// Version 1 repeated a successor once per occurrence.
+			counts := make(successorCounts)
+			for range dec.count() {
+				counts[int32(dec.wordID(vocabLen))]++ //nolint:gosec
+			}
+			next = newTransitions(counts)
+		} else {
+			next = dec.transitions(vocabLen)
+		}
+		chain.chain[encodePrefix(prefix)] = next
+	}
+
+	if dec.err != nil {
+		return nil, fmt.Errorf("decode snapshot: %w", dec.err)
+	}
+	return chain, nil
+}
+
+// SaveFile writes the chain snapshot to path, replacing it atomically.
+func (chain *Chain) SaveFile(path string) error {
+	tmp := path + ".tmp"
+	file, err := os.Create(tmp) //nolint:gosec // path is chosen by the operator
+	if err != nil {
+		return fmt.Errorf("create snapshot: %w", err)
+	}
+	if err := chain.Save(file); err != nil {
+		_ = file.Close()
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("close snapshot: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// LoadFile reads a chain snapshot from path.
+func LoadFile(path string) (*Chain, error) {
+	file, err := os.Open(path) //nolint:gosec // path is chosen by the operator
+	if err != nil {
+		return nil, fmt.Errorf("open snapshot: %w", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	return Load(bufio.NewReader(file))
+}
+
+// decodePrefix is the inverse of encodePrefix.
+func decodePrefix(key string) Prefix {
+	prefix := make(Prefix, len(key)/8)
+	for i := range prefix {
+		prefix[i] = int(binary.LittleEndian.Uint64([]byte(key[i*8:]))) //nolint:gosec
+	}
+	return prefix
+}
+
+// snapshotWriter encodes snapshot fields, remembering the first error.
+type snapshotWriter struct {
+	w   io.Writer
+	err error
+	buf [binary.MaxVarintLen64]byte
+}
+
+func (sw *snapshotWriter) bytes(b []byte) {
+	if sw.err == nil {
+		_, sw.err = sw.w.Write(b)
+	}
+}
+
+func (sw *snapshotWriter) uint32(v uint32) {
+	binary.LittleEndian.PutUint32(sw.buf[:4], v)
+	sw.bytes(sw.buf[:4])
+}
+
+func (sw *snapshotWriter) uvarint(v uint64) {
+	n := binary.PutUvarint(sw.buf[:], v)
+	sw.bytes(sw.buf[:n])
+}
+
+// snapshotReader decodes snapshot fields, remembering the first error.
+// Once an error is recorded every further read returns zero values.
+type snapshotReader struct {
+	buf []byte
+	err error
+}
+
+var errSnapshotTruncated = errors.New("unexpected end of snapshot")
+
+func (sr *snapshotReader) bytes(n int) []byte {
+	if sr.err != nil {
+		return nil
+	}
+	if n > len(sr.buf) {
+		sr.err = errSnapshotTruncated
+		return nil
+	}
+	b := sr.buf[:n]
+	sr.buf = sr.buf[n:]
+	return b
+}
+
+func (sr *snapshotReader) uint32() uint32 {
+	b := sr.bytes(4)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(b)
+}
+
+func (sr *snapshotReader) uvarint() uint64 {
+	if sr.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(sr.buf)
+	if n <= 0 {
+		sr.err = errSnapshotTruncated
+		return 0
+	}
+	sr.buf = sr.buf[n:]
+	return v
+}
+
+// count reads a length field, rejecting values larger than the remaining
+// input so a corrupted snapshot can't trigger a huge allocation.
+func (sr *snapshotReader) count() int {
+	v := sr.uvarint()
+	if v > uint64(len(sr.buf)) {
+		if sr.err == nil {
+			sr.err = errSnapshotTruncated
+		}
+		return 0
+	}
+	return int(v) //nolint:gosec
+}
+
+// occurrences reads a non-zero transition count.
+func (sr *snapshotReader) occurrences() uint32 {
+	v := sr.uvarint()
+	if v == 0 || v > math.MaxUint32 {
+		if sr.err == nil {
+			sr.err = fmt.Errorf("invalid transition count %d", v)
+		}
+		return 0
+	}
+	return uint32(v)
+}
+
+// transitions reads a successor table into place. The counts are summed
+// as they are read, and cum must come out strictly increasing or some
+// successors could never be sampled.
+func (sr *snapshotReader) transitions(vocabLen int) transitions {
+	n := sr.count()
+	next := transitions{ids: make([]int32, n), cum: make([]uint32, n)}
+	var total uint32
+	for i := range n {
+		next.ids[i] = int32(sr.wordID(vocabLen)) //nolint:gosec
+		count := sr.occurrences()
+		if total+count <= total {
+			if sr.err == nil {
+				sr.err = errors.New("transition counts overflow")
+			}
+			return transitions{}
+		}
+		total += count
+		next.cum[i] = total
+	}
+	return next
+}
+
+// wordID reads a word ID and checks that it refers to the vocabulary.
+func (sr *snapshotReader) wordID(vocabLen int) int {
+	v := sr.uvarint()
+	if v >= uint64(vocabLen) { //nolint:gosec
+		if sr.err == nil {
+			sr.err = fmt.Errorf("word id %d out of range", v)
+		}
+		return 0
+	}
+	return int(v) //nolint:gosec
+}
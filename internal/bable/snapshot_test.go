@@ -0,0 +1,127 @@
+package bable
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"reflect"
+	"testing"
+)
+
+const snapshotText = "The workers have nothing to lose but their chains. They have a " +
+	"world to win. Workers of all countries, unite! The history of all " +
+	"hitherto existing society is the history of class struggles."
+
+// saveSnapshot builds a chain from snapshotText and returns it with its
+// snapshot.
+func saveSnapshot(t *testing.T) (*Chain, []byte) {
+	t.Helper()
+	chain := NewChain(3)
+	chain.Build(snapshotText)
+	var buf bytes.Buffer
+	if err := chain.Save(&buf); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	return chain, buf.Bytes()
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	chain, data := saveSnapshot(t)
+
+	loaded, err := Load(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if loaded.maxOrder != chain.maxOrder {
+		t.Errorf("maxOrder = %d, want %d", loaded.maxOrder, chain.maxOrder)
+	}
+	if !reflect.DeepEqual(loaded.vocab, chain.vocab) {
+		t.Errorf("vocab = %q, want %q", loaded.vocab, chain.vocab)
+	}
+	if !reflect.DeepEqual(loaded.wordToID, chain.wordToID) {
+		t.Errorf("wordToID differs after round trip")
+	}
+	if !reflect.DeepEqual(loaded.chain, chain.chain) {
+		t.Errorf("transitions differ after round trip")
+	}
+
+	var again bytes.Buffer
+	if err := loaded.Save(&again); err != nil {
+		t.Fatalf("Save loaded chain: %v", err)
+	}
+	if !bytes.Equal(again.Bytes(), data) {
+		t.Errorf("saving the loaded chain produced a different snapshot")
+	}
+}
+
+func TestSnapshotRejectsCorruption(t *testing.T) {
+	_, data := saveSnapshot(t)
+
+	// resum replaces the trailing checksum so only the edit is wrong.
+	resum := func(b []byte) []byte {
+		binary.LittleEndian.PutUint32(b[len(b)-4:], crc32.ChecksumIEEE(b[:len(b)-4]))
+		return b
+	}
+	tests := []struct {
+		name    string
+		corrupt func([]byte) []byte
+		want    error
+	}{
+		{"flipped byte", func(b []byte) []byte {
+			b[len(b)/2] ^= 0xff
+			return b
+		}, ErrSnapshotChecksum},
+		{"bad checksum", func(b []byte) []byte {
+			b[len(b)-1] ^= 0xff
+			return b
+		}, ErrSnapshotChecksum},
+		{"truncated", func(b []byte) []byte {
+			return b[:len(b)-10]
+		}, ErrSnapshotChecksum},
+		{"bad magic", func(b []byte) []byte {
+			b[0] = 'X'
+			return resum(b)
+		}, ErrSnapshotMagic},
+		{"future version", func(b []byte) []byte {
+			binary.LittleEndian.PutUint32(b[len(snapshotMagic):], snapshotVersion+1)
+			return resum(b)
+		}, ErrSnapshotVersion},
+		{"version zero", func(b []byte) []byte {
+			binary.LittleEndian.PutUint32(b[len(snapshotMagic):], 0)
+			return resum(b)
+		}, ErrSnapshotVersion},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			corrupted := tt.corrupt(bytes.Clone(data))
+			_, err := Load(bytes.NewReader(corrupted))
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Load error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// BenchmarkLoad measures loading a snapshot of the manifest chain, which
+// is what startup pays instead of building the chain.
+func BenchmarkLoad(b *testing.B) {
+	repoRoot(b)
+	text, err := ReadCorpus(corpora()[DefaultLanguage])
+	if err != nil {
+		b.Fatal(err)
+	}
+	chain := NewChain(maxOrder())
+	chain.Build(text)
+	var buf bytes.Buffer
+	if err := chain.Save(&buf); err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(buf.Len()))
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := Load(bytes.NewReader(buf.Bytes())); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
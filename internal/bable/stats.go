@@ -0,0 +1,163 @@
+package bable
+
+import (
+	"math"
+	"math/rand/v2"
+	"strings"
+)
+
+// Stats summarises the size and shape of a chain.
+type Stats struct {
+	// Vocab is the number of distinct tokens, including START and END.
+	Vocab int
+	// Prefixes is the number of distinct prefixes with successors.
+	Prefixes int
+	// Transitions is the number of distinct prefix/successor pairs.
+	Transitions int
+	// AvgBranching and MaxBranching are the mean and largest number of
+	// distinct successors per prefix, over all orders.
+	AvgBranching float64
+	MaxBranching int
+	// DeadEnds is the share of prefixes only ever followed by the end of
+	// a sentence.
+	DeadEnds float64
+	// Orders breaks the figures down by prefix length, shortest first.
+	Orders []OrderStats
+}
+
+// OrderStats describes the prefixes of a single length.
+type OrderStats struct {
+	Order        int
+	Prefixes     int
+	AvgBranching float64
+	MaxBranching int
+	DeadEnds     float64
+	// Forced is the share of prefixes with a single successor, where
+	// generation has no choice but to copy the corpus.
+	Forced float64
+	// Entropy is the conditional entropy of the next word in bits,
+	// weighted by how often each prefix occurs. Low values mean the
+	// chain mostly replays the corpus at this order.
+	Entropy float64
+}
+
+// Stats reports the size and branching of the chain.
+func (chain *Chain) Stats() Stats {
+	st := Stats{
+		Vocab:    len(chain.vocab),
+		Prefixes: len(chain.chain),
+		Orders:   make([]OrderStats, chain.maxOrder),
+	}
+	endID, hasEnd := chain.wordToID[endToken]
+
+	// Accumulate totals per order first, then turn them into averages.
+	occurrences := make([]float64, chain.maxOrder)
+	deadEnds, forced := make([]int, chain.maxOrder), make([]int, chain.maxOrder)
+	allDeadEnds := 0
+	for key, next := range chain.chain {
+		order := len(key) / 8
+		if order < 1 || order > chain.maxOrder {
+			continue
+		}
+		ord := &st.Orders[order-1]
+		branches := len(next.ids)
+		st.Transitions += branches
+		st.MaxBranching = max(st.MaxBranching, branches)
+		ord.Prefixes++
+		ord.AvgBranching += float64(branches)
+		ord.MaxBranching = max(ord.MaxBranching, branches)
+		if branches == 1 {
+			forced[order-1]++
+			if hasEnd && int(next.ids[0]) == endID {
+				deadEnds[order-1]++
+				allDeadEnds++
+			}
+		}
+
+		total := float64(next.total())
+		occurrences[order-1] += total
+		for i := range next.ids {
+			p := float64(next.count(i)) / total
+			ord.Entropy -= total * p * math.Log2(p)
+		}
+	}
+
+	for i := range st.Orders {
+		ord := &st.Orders[i]
+		ord.Order = i + 1
+		if ord.Prefixes == 0 {
+			continue
+		}
+		n := float64(ord.Prefixes)
+		ord.AvgBranching /= n
+		ord.DeadEnds = float64(deadEnds[i]) / n
+		ord.Forced = float64(forced[i]) / n
+		ord.Entropy /= occurrences[i]
+	}
+	if st.Prefixes > 0 {
+		st.AvgBranching = float64(st.Transitions) / float64(st.Prefixes)
+		st.DeadEnds = float64(allDeadEnds) / float64(st.Prefixes)
+	}
+	return st
+}
+
+// Verbatim generates samples sentences at the chain's full order and
+// returns the share that repeat a sentence of source word for word,
+// ignoring case and final punctuation. source should be the corpus the
+// chain was built from.
+func (chain *Chain) Verbatim(rng *rand.Rand, source string, samples int) float64 {
+	known := make(map[string]bool)
+	for _, sentence := range splitIntoSentences(source) {
+		known[sentenceKey(sentence)] = true
+	}
+
+	copied, generated := 0, 0
+	for range samples {
+		sentence := chain.generateOneSentence(rng, chain.maxOrder, DefaultCreativity(), nil)
+		if sentence == "" {
+			continue
+		}
+		generated++
+		if known[sentenceKey(sentence)] {
+			copied++
+		}
+	}
+	if generated == 0 {
+		return 0
+	}
+	return float64(copied) / float64(generated)
+}
+
+// sentenceKey normalizes a sentence for verbatim comparison.
+func sentenceKey(sentence string) string {
+	tokens := tokenize(strings.ToLower(sentence))
+	for len(tokens) > 0 && isPunct(tokens[len(tokens)-1]) {
+		tokens = tokens[:len(tokens)-1]
+	}
+	return strings.Join(tokens, " ")
+}
+
+// Report is the quality report of one language's shared chain.
+type Report struct {
+	Lang string
+	Stats
+	// Verbatim is the share of sampled sentences copied from the corpus,
+	// or -1 when the corpus file can't be read, as happens when only a
+	// snapshot is deployed.
+	Verbatim float64
+	Samples  int
+}
+
+// SharedReport reports on the shared chain for lang, checking samples
+// generated sentences against its configured corpus.
+func SharedReport(rng *rand.Rand, lang string, samples int) Report {
+	if _, ok := corpora()[lang]; !ok {
+		lang = DefaultLanguage
+	}
+	chain := Shared(lang)
+	report := Report{Lang: lang, Stats: chain.Stats(), Verbatim: -1, Samples: samples}
+	if text, err := ReadCorpus(corpora()[lang]); err == nil {
+		report.Verbatim = chain.Verbatim(rng, text, samples)
+	}
+	return report
+}
@@ -0,0 +1,98 @@
+package bable
+
+import (
+	"math/rand/v2"
+	"strings"
+)
+
+// Topic is a set of words generation is steered toward. Successors that
+// are topic words are favoured, and so are successors that often lead to
+// one, which draws sentences along branches that reach the topic instead
+// of only picking it up when it happens to be one step away.
+type Topic struct {
+	// targets holds the word IDs of the topic words.
+	targets map[int32]bool
+	// near maps predecessors of topic words to the share of their
+	// occurrences that are followed by one.
+	near map[int32]float64
+}
+
+// Topic returns the topic made of the chain's words matching seeds, which
+// are compared case-insensitively and also match their plural. It returns
+// nil when none of the seeds occur in the corpus.
+func (chain *Chain) Topic(seeds []string) *Topic {
+	if len(seeds) == 0 {
+		return nil
+	}
+	want := make(map[string]bool, len(seeds)*2)
+	for _, seed := range seeds {
+		seed = strings.ToLower(seed)
+		want[seed] = true
+		want[seed+"s"] = true
+	}
+
+	topic := &Topic{targets: make(map[int32]bool), near: make(map[int32]float64)}
+	for id, word := range chain.vocab {
+		if want[strings.ToLower(word)] {
+			topic.targets[int32(id)] = true //nolint:gosec
+		}
+	}
+	if len(topic.targets) == 0 {
+		return nil
+	}
+
+	preds := chain.predecessors()
+	for target := range topic.targets {
+		for _, pred := range preds[target] {
+			next := chain.chain[encodePrefix(Prefix{int(pred)})]
+			for i, id := range next.ids {
+				if id == target {
+					topic.near[pred] += float64(next.count(i)) / float64(next.total())
+				}
+			}
+		}
+	}
+	return topic
+}
+
+// buildPredecessors indexes which words precede each word, from the
+// order-1 transitions.
+func (chain *Chain) buildPredecessors() map[int32][]int32 {
+	preds := make(map[int32][]int32)
+	for key, next := range chain.chain {
+		if len(key) != 8 {
+			continue
+		}
+		pred := int32(decodePrefix(key)[0]) //nolint:gosec
+		for _, id := range next.ids {
+			preds[id] = append(preds[id], pred)
+		}
+	}
+	return preds
+}
+
+// weight returns how much more likely id should be picked than the
+// corpus suggests, given the focus from Creativity.
+func (topic *Topic) weight(id int32, focus float64) float64 {
+	if topic.targets[id] {
+		return focus
+	}
+	return 1 + (focus-1)*min(topic.near[id], 1)
+}
+
+// sampleToward picks a successor like sample does, with each successor's
+// count scaled by its weight toward topic.
+func (t *transitions) sampleToward(rng *rand.Rand, topic *Topic, focus float64) int {
+	total := 0.0
+	for i, id := range t.ids {
+		total += float64(t.count(i)) * topic.weight(id, focus)
+	}
+	r := rng.Float64() * total
+	for i, id := range t.ids {
+		r -= float64(t.count(i)) * topic.weight(id, focus)
+		if r < 0 {
+			return int(id)
+		}
+	}
+	return int(t.ids[len(t.ids)-1])
+}
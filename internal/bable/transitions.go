@@ -0,0 +1,73 @@
+package bable
+
+import (
+	"math/rand/v2"
+	"slices"
+	"sort"
+)
+
+// transitions holds the distinct successors of one prefix together with
+// their cumulative occurrence counts. Memory grows with the number of
+// distinct transitions rather than with corpus size, and sampling by binary
+// search over cum keeps the same distribution as picking uniformly from one
+// slot per occurrence.
+type transitions struct {
+	ids []int32
+	// cum[i] is the total count of ids[0..i].
+	cum []uint32
+}
+
+// successorCounts counts the successors of one prefix while a chain is
+// built. Counting in a map keeps Build linear in the corpus; the counts
+// become transitions once all the text is read.
+type successorCounts map[int32]uint32
+
+// newTransitions turns counts into transitions with the successors in ID
+// order, so equal counts always give equal tables.
+func newTransitions(counts successorCounts) transitions {
+	t := transitions{
+		ids: make([]int32, 0, len(counts)),
+		cum: make([]uint32, len(counts)),
+	}
+	for id := range counts {
+		t.ids = append(t.ids, id)
+	}
+	slices.Sort(t.ids)
+	var total uint32
+	for i, id := range t.ids {
+		total += counts[id]
+		t.cum[i] = total
+	}
+	return t
+}
+
+// addTo adds the occurrences recorded in t to counts.
+func (t *transitions) addTo(counts successorCounts) {
+	for i, id := range t.ids {
+		counts[id] += t.count(i)
+	}
+}
+
+// total returns the number of recorded occurrences.
+func (t *transitions) total() uint32 {
+	if len(t.cum) == 0 {
+		return 0
+	}
+	return t.cum[len(t.cum)-1]
+}
+
+// count returns the number of occurrences of the i-th distinct successor.
+func (t *transitions) count(i int) uint32 {
+	if i == 0 {
+		return t.cum[0]
+	}
+	return t.cum[i] - t.cum[i-1]
+}
+
+// sample picks a successor with probability proportional to its count.
+// It must not be called on an empty transitions.
+func (t *transitions) sample(rng *rand.Rand) int {
+	r := rng.Uint32N(t.total())
+	i := sort.Search(len(t.cum), func(i int) bool { return t.cum[i] > r })
+	return int(t.ids[i])
+}
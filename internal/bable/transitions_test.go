@@ -0,0 +1,84 @@
+package bable
+
+import (
+	"math"
+	"math/rand/v2"
+	"reflect"
+	"testing"
+)
+
+func TestTransitionsSample(t *testing.T) {
+	tests := []struct {
+		name   string
+		counts successorCounts
+	}{
+		{"single", successorCounts{7: 3}},
+		{"even", successorCounts{1: 5, 2: 5, 3: 5, 4: 5}},
+		{"weighted", successorCounts{1: 1, 2: 3, 3: 6}},
+		{"skewed", successorCounts{10: 1, 20: 99}},
+		{"sparse ids", successorCounts{0: 2, 500: 1, 90000: 1}},
+	}
+	const draws = 200000
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := newTransitions(tt.counts)
+			var total uint32
+			for _, n := range tt.counts {
+				total += n
+			}
+			if next.total() != total {
+				t.Fatalf("total() = %d, want %d", next.total(), total)
+			}
+
+			rng := rand.New(rand.NewPCG(1, 2)) //nolint:gosec
+			seen := make(map[int]int)
+			for range draws {
+				seen[next.sample(rng)]++
+			}
+			for id := range seen {
+				if _, ok := tt.counts[int32(id)]; !ok { //nolint:gosec
+					t.Errorf("sampled %d, which is not a successor", id)
+				}
+			}
+			for id, n := range tt.counts {
+				want := float64(n) / float64(total)
+				got := float64(seen[int(id)]) / draws
+				if math.Abs(got-want) > 0.01 {
+					t.Errorf("successor %d drawn %.3f of the time, want %.3f", id, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestNewTransitionsSortsIDs(t *testing.T) {
+	next := newTransitions(successorCounts{9: 1, 3: 2, 5: 4})
+	wantIDs := []int32{3, 5, 9}
+	wantCum := []uint32{2, 6, 7}
+	if !reflect.DeepEqual(next.ids, wantIDs) || !reflect.DeepEqual(next.cum, wantCum) {
+		t.Errorf("ids, cum = %v, %v, want %v, %v", next.ids, next.cum, wantIDs, wantCum)
+	}
+}
+
+func TestBuildCountsSuccessors(t *testing.T) {
+	const text = "The cat sat. The cat ran. The dog sat."
+	whole := NewChain(2)
+	whole.Build(text)
+
+	next := whole.chain[encodePrefix(Prefix{whole.wordToID["The"]})]
+	got := make(map[string]uint32)
+	for i, id := range next.ids {
+		got[whole.vocab[id]] = next.count(i)
+	}
+	if len(got) != 2 || got["cat"] != 2 || got["dog"] != 1 {
+		t.Errorf("successors of The = %v, want cat:2 dog:1", got)
+	}
+
+	// Building in two parts merges into the same tables.
+	parts := NewChain(2)
+	parts.Build("The cat sat. The cat ran.")
+	parts.Build("The dog sat.")
+	if !reflect.DeepEqual(parts.chain, whole.chain) {
+		t.Errorf("chain built in two parts differs from one built at once")
+	}
+}
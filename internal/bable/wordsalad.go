@@ -0,0 +1,120 @@
+package bable
+
+import (
+	"fmt"
+	"iter"
+	"log/slog"
+	"math/rand/v2"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// WordSalad generates text by drawing words uniformly at random from the
+// words_manifesto list. It has no notion of grammar, which makes it a cheap
+// baseline to compare the Markov output against.
+type WordSalad struct {
+	// Seeds are mixed in among the random words when set through About.
+	Seeds []string
+}
+
+// saladWords loads the word list once per process. Without it the word
+// salad generates nothing.
+var saladWords = sync.OnceValue(func() []string {
+	words, err := readWordList("words_manifesto")
+	if err != nil {
+		slog.Error("word list unreadable, word salad is empty", "error", err)
+	}
+	return words
+})
+
+// Sentences returns numSentences capitalised sentences of random words.
+func (s WordSalad) Sentences(rng *rand.Rand, numSentences int) string {
+	sentences := make([]string, 0, numSentences)
+	for range numSentences {
+		sentence := s.words(rng, 6+rng.IntN(14))
+		if sentence == "" {
+			continue
+		}
+		sentences = append(sentences, capitalise(sentence)+".")
+	}
+	return strings.Join(sentences, " ")
+}
+
+// Title returns between two and maxWords random words.
+func (s WordSalad) Title(rng *rand.Rand, maxWords int) string {
+	n := maxWords
+	if n > 2 {
+		n = 2 + rng.IntN(maxWords-1)
+	}
+	return capitalise(s.words(rng, n))
+}
+
+// Slug returns a slug of random non-stop words.
+func (s WordSalad) Slug(rng *rand.Rand, wordCount int) string {
+	return slugify(strings.Fields(s.words(rng, wordCount*3)), wordCount)
+}
+
+// ListItem returns a short capitalised run of random words.
+func (s WordSalad) ListItem(rng *rand.Rand) string {
+	return capitalise(s.words(rng, 4+rng.IntN(7)))
+}
+
+// Stream returns an endless sequence of random-word sentences.
+func (s WordSalad) Stream(rng *rand.Rand) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		if len(saladWords()) == 0 {
+			return
+		}
+		for yield(s.Sentences(rng, 1)) {
+		}
+	}
+}
+
+// About returns a word salad that mixes seeds in among its words.
+func (s WordSalad) About(seeds []string) Generator {
+	s.Seeds = seeds
+	return s
+}
+
+func (s WordSalad) words(rng *rand.Rand, n int) string {
+	list := saladWords()
+	if len(list) == 0 {
+		return ""
+	}
+	words := make([]string, n)
+	for i := range words {
+		if len(s.Seeds) > 0 && rng.IntN(10) == 0 {
+			words[i] = s.Seeds[rng.IntN(len(s.Seeds))]
+			continue
+		}
+		words[i] = list[rng.IntN(len(list))]
+	}
+	return strings.Join(words, " ")
+}
+
+// capitalise upper-cases the first letter of s.
+func capitalise(s string) string {
+	runes := []rune(s)
+	if len(runes) == 0 {
+		return s
+	}
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
+
+// readWordList reads a newline-separated word list, skipping blank lines.
+func readWordList(path string) ([]string, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // path is fixed by the caller
+	if err != nil {
+		return nil, fmt.Errorf("read word list: %w", err)
+	}
+	var words []string
+	for line := range strings.Lines(string(data)) {
+		if word := strings.TrimSpace(line); word != "" {
+			words = append(words, word)
+		}
+	}
+	return words, nil
+}
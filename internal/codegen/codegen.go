@@ -0,0 +1,342 @@
+// Package codegen produces plausible looking but meaningless source code
+// for tarpit pages, for crawlers that favour pages containing code.
+// Snippets come from a small statement grammar rendered in the syntax of
+// each language, with identifiers made from words the caller supplies so
+// the code matches the topic of the surrounding text.
+//
+// The grammar is written by hand rather than learned from a code corpus.
+// Erebus ships only prose corpora, and a token-level chain over code
+// breaks brackets and indentation often enough that the output stops
+// looking like code to the heuristics crawlers rank pages by. A corpus
+// backed generator can be added next to this one once there are code
+// corpora to deploy.
+package codegen
+
+import (
+	"math/rand/v2"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// dialect renders the grammar's constructs in one language's syntax.
+type dialect struct {
+	indent  string
+	comment string
+	// ident joins words into a local name, exported into the name of a
+	// function where the language distinguishes the two.
+	ident    func(words []string) string
+	exported func(words []string) string
+	// header writes imports and anything else preceding the function.
+	header func(g *gen)
+	// funcOpen writes the signature and opens the body.
+	funcOpen   func(g *gen, name string, params []string)
+	ifOpen     func(cond string) string
+	elseOpen   string
+	forOpen    func(item, coll string) string
+	blockClose string
+	declare    func(name, value string) string
+	// declareCall declares name from a call that may fail.
+	declareCall func(name, call string) string
+	assign      func(name, value string) string
+	ret         func(value string) string
+	// errCheck writes a guard after a call that may fail, if the
+	// language reports errors that way.
+	errCheck func(g *gen)
+	appendTo func(coll, item string) string
+	lenOf    func(x string) string
+	isEmpty  func(x string) string
+	isNull   func(x string) string
+	and      string
+	equals   string
+	falseLit string
+	quote    string
+}
+
+// dialects holds every supported language by the name used in the
+// language-x class of the rendered code element.
+var dialects = map[string]*dialect{
+	"go":         goDialect,
+	"python":     pythonDialect,
+	"javascript": javascriptDialect,
+}
+
+// Languages lists the supported languages in sorted order.
+func Languages() []string {
+	names := make([]string, 0, len(dialects))
+	for name := range dialects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Snippet returns a function in lang built from words. It returns an
+// empty string for unknown languages or when words has no usable word.
+// All choices are drawn from rng, so a seeded rng reproduces the snippet.
+func Snippet(rng *rand.Rand, lang string, words []string) string {
+	d, ok := dialects[lang]
+	if !ok {
+		return ""
+	}
+	words = identWords(words)
+	if len(words) == 0 {
+		return ""
+	}
+
+	g := &gen{rng: rng, words: words, d: d}
+	d.header(g)
+
+	var params []string
+	for range 1 + rng.IntN(3) {
+		params = g.appendUnique(params, d.ident(g.pickWords(1)))
+	}
+	name := d.exported(g.pickWords(2))
+	g.line(d.comment + " " + name + " " + strings.Join(g.pickWords(3+rng.IntN(4)), " ") + ".")
+	d.funcOpen(g, name, params)
+	g.vars = params
+
+	g.depth++
+	for range 3 + rng.IntN(4) {
+		if g.lines > maxLines {
+			break
+		}
+		g.statement(2)
+	}
+	g.line(d.ret(g.expr()))
+	g.depth--
+	g.line(d.blockClose)
+	return strings.TrimRight(g.b.String(), "\n")
+}
+
+// maxLines is the length after which no further top-level statements
+// are added to a snippet.
+const maxLines = 24
+
+// gen accumulates one snippet.
+type gen struct {
+	rng   *rand.Rand
+	words []string
+	d     *dialect
+	b     strings.Builder
+	lines int
+	depth int
+	// vars are the identifiers in scope, used to make expressions.
+	vars []string
+}
+
+// line writes s on its own line at the current depth. Empty strings are
+// skipped, so dialects without closing braces can leave blockClose empty.
+func (g *gen) line(s string) {
+	if s == "" {
+		return
+	}
+	g.b.WriteString(strings.Repeat(g.d.indent, g.depth))
+	g.b.WriteString(s)
+	g.b.WriteByte('\n')
+	g.lines++
+}
+
+// blank writes an empty line.
+func (g *gen) blank() {
+	g.b.WriteByte('\n')
+}
+
+// pickWords returns n random words, never the same one twice in a row
+// unless there is only one to choose from.
+func (g *gen) pickWords(n int) []string {
+	picked := make([]string, n)
+	for i := range picked {
+		picked[i] = g.words[g.rng.IntN(len(g.words))]
+		if i > 0 && picked[i] == picked[i-1] && len(g.words) > 1 {
+			picked[i] = g.words[(slices.Index(g.words, picked[i])+1)%len(g.words)]
+		}
+	}
+	return picked
+}
+
+// appendUnique appends name unless it is already in names.
+func (g *gen) appendUnique(names []string, name string) []string {
+	for _, n := range names {
+		if n == name {
+			return names
+		}
+	}
+	return append(names, name)
+}
+
+// variable returns an identifier in scope.
+func (g *gen) variable() string {
+	return g.vars[g.rng.IntN(len(g.vars))]
+}
+
+// statement writes one statement, nesting blocks up to depth more levels.
+func (g *gen) statement(depth int) {
+	d := g.d
+	kind := g.rng.IntN(6)
+	if depth == 0 {
+		kind = g.rng.IntN(2)
+	}
+
+	switch kind {
+	case 0:
+		name := d.ident(g.pickWords(2))
+		g.line(d.declare(name, g.expr()))
+		g.vars = g.appendUnique(g.vars, name)
+	case 1:
+		name := d.ident(g.pickWords(2))
+		g.line(d.declareCall(name, g.call()))
+		g.vars = g.appendUnique(g.vars, name)
+		if d.errCheck != nil {
+			d.errCheck(g)
+		}
+	case 2, 3:
+		g.line(d.ifOpen(g.cond()))
+		g.block(depth - 1)
+		if g.rng.IntN(3) == 0 {
+			g.line(d.elseOpen)
+			g.block(depth - 1)
+		}
+		g.line(d.blockClose)
+	case 4:
+		item := d.ident(g.pickWords(1))
+		coll, target := g.variable(), g.variable()
+		g.line(d.forOpen(item, coll))
+		scope := len(g.vars)
+		g.vars = g.appendUnique(g.vars, item)
+		g.depth++
+		g.line(d.appendTo(target, item))
+		g.depth--
+		g.block(depth - 1)
+		g.vars = g.vars[:scope]
+		g.line(d.blockClose)
+	default:
+		g.line(d.assign(g.variable(), g.expr()))
+	}
+}
+
+// block writes the indented body of a block statement, returning early
+// from it some of the time.
+func (g *gen) block(depth int) {
+	g.depth++
+	for range 1 + g.rng.IntN(2) {
+		g.statement(depth)
+	}
+	if g.rng.IntN(3) == 0 {
+		g.line(g.d.ret(g.expr()))
+	}
+	g.depth--
+}
+
+// expr returns a value expression.
+func (g *gen) expr() string {
+	d := g.d
+	switch g.rng.IntN(6) {
+	case 0:
+		return g.call()
+	case 1:
+		return d.lenOf(g.variable())
+	case 2:
+		return d.quote + strings.Join(g.pickWords(1+g.rng.IntN(3)), " ") + d.quote
+	case 3:
+		return g.variable() + " + " + g.number()
+	case 4:
+		return g.variable() + "." + d.ident(g.pickWords(1))
+	default:
+		return g.variable()
+	}
+}
+
+// call returns a function or method call expression.
+func (g *gen) call() string {
+	d := g.d
+	args := make([]string, g.rng.IntN(3))
+	for i := range args {
+		if g.rng.IntN(3) == 0 {
+			args[i] = g.number()
+		} else {
+			args[i] = g.variable()
+		}
+	}
+	name := d.exported(g.pickWords(2))
+	if g.rng.IntN(2) == 0 {
+		name = g.variable() + "." + name
+	}
+	return name + "(" + strings.Join(args, ", ") + ")"
+}
+
+// cond returns a boolean expression.
+func (g *gen) cond() string {
+	d := g.d
+	switch g.rng.IntN(5) {
+	case 0:
+		return d.isEmpty(g.variable())
+	case 1:
+		return d.lenOf(g.variable()) + " > " + g.number()
+	case 2:
+		return d.isNull(g.variable())
+	case 3:
+		return g.variable() + " " + d.equals + " " + d.falseLit + " " + d.and + " " +
+			d.lenOf(g.variable()) + " < " + g.number()
+	default:
+		return g.call()
+	}
+}
+
+func (g *gen) number() string {
+	return []string{"0", "1", "2", "8", "16", "64", "255", "1024"}[g.rng.IntN(8)]
+}
+
+// keywords are reserved in at least one of the languages and can't be
+// used as names.
+var keywords = map[string]bool{
+	"and": true, "as": true, "assert": true, "async": true, "await": true,
+	"break": true, "case": true, "chan": true, "class": true, "const": true,
+	"continue": true, "def": true, "default": true, "defer": true, "del": true,
+	"delete": true, "elif": true, "else": true, "except": true, "export": true,
+	"false": true, "finally": true, "for": true, "from": true, "func": true,
+	"function": true, "global": true, "goto": true, "if": true, "import": true,
+	"in": true, "interface": true, "is": true, "lambda": true, "let": true,
+	"map": true, "new": true, "nil": true, "none": true, "not": true,
+	"null": true, "or": true, "package": true, "pass": true, "raise": true,
+	"range": true, "return": true, "select": true, "struct": true, "switch": true,
+	"this": true, "true": true, "try": true, "type": true, "typeof": true,
+	"var": true, "void": true, "while": true, "with": true, "yield": true,
+}
+
+// identWords reduces words to lower-case ASCII letters, dropping any that
+// end up too short to read as a name, repeat an earlier word or are
+// keywords.
+func identWords(words []string) []string {
+	var out []string
+	for _, w := range words {
+		w = strings.Map(func(r rune) rune {
+			if r < unicode.MaxASCII && unicode.IsLetter(r) {
+				return unicode.ToLower(r)
+			}
+			return -1
+		}, w)
+		if len(w) >= 3 && !keywords[w] && !slices.Contains(out, w) {
+			out = append(out, w)
+		}
+	}
+	return out
+}
+
+// camel joins words as camelCase, upper-casing the first one too if
+// exported is set.
+func camel(words []string, exported bool) string {
+	var b strings.Builder
+	for i, w := range words {
+		if i > 0 || exported {
+			w = strings.ToUpper(w[:1]) + w[1:]
+		}
+		b.WriteString(w)
+	}
+	return b.String()
+}
+
+func snake(words []string) string {
+	return strings.Join(words, "_")
+}
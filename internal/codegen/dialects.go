@@ -0,0 +1,130 @@
+package codegen
+
+import "strings"
+
+// goTypes are the parameter and result types of generated Go functions.
+var goTypes = []string{
+	"string", "int", "[]byte", "[]string", "map[string]int",
+	"context.Context", "*Config", "io.Reader", "time.Duration", "bool",
+}
+
+var goDialect = &dialect{
+	indent:   "\t",
+	comment:  "//",
+	ident:    func(words []string) string { return camel(words, false) },
+	exported: func(words []string) string { return camel(words, true) },
+	header: func(g *gen) {
+		if g.rng.IntN(2) == 0 {
+			return
+		}
+		g.line("package " + g.words[g.rng.IntN(len(g.words))])
+		g.blank()
+		g.line(`import (`)
+		g.line("\t" + `"errors"`)
+		g.line("\t" + `"fmt"`)
+		g.line(`)`)
+		g.blank()
+	},
+	funcOpen: func(g *gen, name string, params []string) {
+		typed := make([]string, len(params))
+		for i, p := range params {
+			typed[i] = p + " " + goTypes[g.rng.IntN(len(goTypes))]
+		}
+		result := "*" + camel(g.pickWords(1), true)
+		g.line("func " + name + "(" + strings.Join(typed, ", ") + ") (" + result + ", error) {")
+	},
+	ifOpen:     func(cond string) string { return "if " + cond + " {" },
+	elseOpen:   "} else {",
+	forOpen:    func(item, coll string) string { return "for _, " + item + " := range " + coll + " {" },
+	blockClose: "}",
+	declare:    func(name, value string) string { return name + " := " + value },
+	declareCall: func(name, call string) string {
+		return name + ", err := " + call
+	},
+	assign: func(name, value string) string { return name + " = " + value },
+	ret:    func(value string) string { return "return " + value + ", nil" },
+	errCheck: func(g *gen) {
+		g.line("if err != nil {")
+		g.depth++
+		g.line(`return nil, fmt.Errorf("` + strings.Join(g.pickWords(2), " ") + `: %w", err)`)
+		g.depth--
+		g.line("}")
+	},
+	appendTo: func(coll, item string) string { return coll + " = append(" + coll + ", " + item + ")" },
+	lenOf:    func(x string) string { return "len(" + x + ")" },
+	isEmpty:  func(x string) string { return "len(" + x + ") == 0" },
+	isNull:   func(x string) string { return x + " == nil" },
+	and:      "&&",
+	equals:   "==",
+	falseLit: "false",
+	quote:    `"`,
+}
+
+var pythonDialect = &dialect{
+	indent:   "    ",
+	comment:  "#",
+	ident:    snake,
+	exported: snake,
+	header: func(g *gen) {
+		if g.rng.IntN(2) == 0 {
+			return
+		}
+		g.line("import logging")
+		g.line("from typing import Any, Optional")
+		g.blank()
+		g.blank()
+	},
+	funcOpen: func(g *gen, name string, params []string) {
+		g.line("def " + name + "(" + strings.Join(params, ", ") + "):")
+	},
+	ifOpen:      func(cond string) string { return "if " + cond + ":" },
+	elseOpen:    "else:",
+	forOpen:     func(item, coll string) string { return "for " + item + " in " + coll + ":" },
+	blockClose:  "",
+	declare:     func(name, value string) string { return name + " = " + value },
+	declareCall: func(name, call string) string { return name + " = " + call },
+	assign:      func(name, value string) string { return name + " = " + value },
+	ret:         func(value string) string { return "return " + value },
+	appendTo:    func(coll, item string) string { return coll + ".append(" + item + ")" },
+	lenOf:       func(x string) string { return "len(" + x + ")" },
+	isEmpty:     func(x string) string { return "not " + x },
+	isNull:      func(x string) string { return x + " is None" },
+	and:         "and",
+	equals:      "==",
+	falseLit:    "False",
+	quote:       `"`,
+}
+
+var javascriptDialect = &dialect{
+	indent:   "  ",
+	comment:  "//",
+	ident:    func(words []string) string { return camel(words, false) },
+	exported: func(words []string) string { return camel(words, false) },
+	header: func(g *gen) {
+		if g.rng.IntN(2) == 0 {
+			return
+		}
+		g.line("import { " + camel(g.pickWords(2), false) + " } from './" + g.words[g.rng.IntN(len(g.words))] + ".js';")
+		g.blank()
+	},
+	funcOpen: func(g *gen, name string, params []string) {
+		prefix := []string{"function ", "export function ", "export async function "}[g.rng.IntN(3)]
+		g.line(prefix + name + "(" + strings.Join(params, ", ") + ") {")
+	},
+	ifOpen:      func(cond string) string { return "if (" + cond + ") {" },
+	elseOpen:    "} else {",
+	forOpen:     func(item, coll string) string { return "for (const " + item + " of " + coll + ") {" },
+	blockClose:  "}",
+	declare:     func(name, value string) string { return "const " + name + " = " + value + ";" },
+	declareCall: func(name, call string) string { return "const " + name + " = " + call + ";" },
+	assign:      func(name, value string) string { return name + " = " + value + ";" },
+	ret:         func(value string) string { return "return " + value + ";" },
+	appendTo:    func(coll, item string) string { return coll + ".push(" + item + ");" },
+	lenOf:       func(x string) string { return x + ".length" },
+	isEmpty:     func(x string) string { return "!" + x },
+	isNull:      func(x string) string { return x + " === null" },
+	and:         "&&",
+	equals:      "===",
+	falseLit:    "false",
+	quote:       "'",
+}
@@ -0,0 +1,176 @@
+package corpus
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"text/tabwriter"
+
+	"Erebus/internal/bable"
+)
+
+// Run executes an "erebus corpus" subcommand with the given arguments.
+func Run(args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		return errors.New("usage: erebus corpus build|stats [flags] <path>")
+	}
+	switch args[0] {
+	case "build":
+		return runBuild(args[1:], stdout, stderr)
+	case "stats":
+		return runStats(args[1:], stdout, stderr)
+	default:
+		return fmt.Errorf("unknown corpus command %q", args[0])
+	}
+}
+
+// runBuild ingests a directory and writes a normalized corpus,
+// or a chain snapshot when -snapshot is given.
+func runBuild(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("corpus build", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	out := fs.String("o", "", "output file (default stdout for a corpus)")
+	snapshot := fs.Bool("snapshot", false, "write a chain snapshot instead of a text corpus")
+	order := fs.Int("order", bable.DefaultMaxOrder, "maximum chain order for -snapshot")
+	minWords := fs.Int("min-words", 6, "drop paragraphs with fewer words")
+	fs.Usage = func() {
+		_, _ = fmt.Fprintln(stderr, "usage: erebus corpus build [flags] <dir>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("expected exactly one directory")
+	}
+
+	res, err := Ingest(fs.Arg(0), Options{MinWords: *minWords})
+	if err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(stderr,
+		"read %d files (%d skipped): kept %d paragraphs, dropped %d duplicates and %d boilerplate\n",
+		res.Files, res.Skipped, len(res.Paragraphs), res.Duplicates, res.Boilerplate)
+
+	if *snapshot {
+		if *out == "" {
+			return errors.New("-snapshot requires -o")
+		}
+		chain := bable.NewChain(*order)
+		chain.Build(res.Text())
+		if err := chain.SaveFile(*out); err != nil {
+			return err
+		}
+		st := chain.Stats()
+		_, _ = fmt.Fprintf(stderr, "wrote snapshot %s: %d words, %d prefixes, %d transitions\n",
+			*out, st.Vocab, st.Prefixes, st.Transitions)
+		return nil
+	}
+
+	if *out == "" {
+		_, err := io.WriteString(stdout, res.Text())
+		return err
+	}
+	if err := os.WriteFile(*out, []byte(res.Text()), 0o600); err != nil {
+		return fmt.Errorf("write corpus: %w", err)
+	}
+	_, _ = fmt.Fprintf(stderr, "wrote corpus %s\n", *out)
+	return nil
+}
+
+// runStats builds a chain from a corpus file or directory, or loads one
+// from a snapshot, and prints its quality report.
+func runStats(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("corpus stats", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	snapshot := fs.String("snapshot", "", "report on this chain snapshot instead of building one")
+	order := fs.Int("order", bable.DefaultMaxOrder, "maximum chain order when building")
+	samples := fs.Int("samples", 500, "sentences generated to measure verbatim copying")
+	minWords := fs.Int("min-words", 6, "drop paragraphs with fewer words when reading a directory")
+	fs.Usage = func() {
+		_, _ = fmt.Fprintln(stderr, "usage: erebus corpus stats [flags] <corpus file or dir>")
+		_, _ = fmt.Fprintln(stderr, "       erebus corpus stats -snapshot <file> [corpus file or dir]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if fs.NArg() > 1 || (fs.NArg() == 0 && *snapshot == "") {
+		fs.Usage()
+		return errors.New("expected one corpus file or directory")
+	}
+
+	var text string
+	if fs.NArg() == 1 {
+		var err error
+		if text, err = readText(fs.Arg(0), *minWords); err != nil {
+			return err
+		}
+	}
+
+	var chain *bable.Chain
+	if *snapshot != "" {
+		var err error
+		if chain, err = bable.LoadFile(*snapshot); err != nil {
+			return err
+		}
+	} else {
+		chain = bable.NewChain(*order)
+		chain.Build(text)
+	}
+
+	verbatim := -1.0
+	if text != "" {
+		verbatim = chain.Verbatim(rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())), text, *samples) //nolint:gosec
+	}
+	return writeReport(stdout, chain.Stats(), verbatim)
+}
+
+// readText returns the corpus text of a plain file, or of every supported
+// file below a directory.
+func readText(path string, minWords int) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return bable.ReadCorpus(path)
+	}
+	res, err := Ingest(path, Options{MinWords: minWords})
+	if err != nil {
+		return "", err
+	}
+	return res.Text(), nil
+}
+
+// writeReport prints st as a summary followed by a table per order.
+// A negative verbatim share is left out.
+func writeReport(w io.Writer, st bable.Stats, verbatim float64) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintf(tw, "vocabulary\t%d\n", st.Vocab)
+	_, _ = fmt.Fprintf(tw, "prefixes\t%d\n", st.Prefixes)
+	_, _ = fmt.Fprintf(tw, "transitions\t%d\n", st.Transitions)
+	_, _ = fmt.Fprintf(tw, "branching\t%.2f avg, %d max\n", st.AvgBranching, st.MaxBranching)
+	_, _ = fmt.Fprintf(tw, "dead ends\t%.1f%%\n", 100*st.DeadEnds)
+	if verbatim >= 0 {
+		_, _ = fmt.Fprintf(tw, "verbatim\t%.1f%%\n", 100*verbatim)
+	}
+	_, _ = fmt.Fprintln(tw)
+	_, _ = fmt.Fprintln(tw, "order\tprefixes\tavg branching\tmax branching\tdead ends\tforced\tentropy (bits)")
+	for _, ord := range st.Orders {
+		_, _ = fmt.Fprintf(tw, "%d\t%d\t%.2f\t%d\t%.1f%%\t%.1f%%\t%.2f\n",
+			ord.Order, ord.Prefixes, ord.AvgBranching, ord.MaxBranching,
+			100*ord.DeadEnds, 100*ord.Forced, ord.Entropy)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,293 @@
+// Package corpus turns directories of text, HTML and Markdown into
+// normalized corpora the Markov chains can be built from.
+package corpus
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Options controls what Ingest keeps.
+type Options struct {
+	// MinWords drops paragraphs shorter than this many words,
+	// which is where navigation and captions usually end up.
+	MinWords int
+}
+
+// Result is a normalized corpus with counters describing how it was made.
+type Result struct {
+	// Paragraphs holds the kept paragraphs in the order they were found.
+	Paragraphs []string
+
+	Files       int
+	Skipped     int
+	Duplicates  int
+	Boilerplate int
+}
+
+// format is the kind of markup a file contains.
+type format int
+
+const (
+	formatText format = iota
+	formatMarkdown
+	formatHTML
+)
+
+// formats maps the supported extensions to their format.
+// Each may additionally end in .gz.
+var formats = map[string]format{
+	".txt":      formatText,
+	".text":     formatText,
+	".md":       formatMarkdown,
+	".markdown": formatMarkdown,
+	".html":     formatHTML,
+	".htm":      formatHTML,
+	".xhtml":    formatHTML,
+}
+
+// Ingest walks root and collects the paragraphs of every supported file,
+// stripping markup, dropping boilerplate and removing duplicates.
+// Hidden files and directories are skipped.
+func Ingest(root string, opts Options) (Result, error) {
+	var res Result
+	seen := make(map[[sha256.Size]byte]bool)
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(d.Name(), ".") && path != root {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		name := strings.ToLower(d.Name())
+		gzipped := strings.HasSuffix(name, ".gz")
+		f, ok := formats[filepath.Ext(strings.TrimSuffix(name, ".gz"))]
+		if !ok {
+			res.Skipped++
+			return nil
+		}
+
+		text, err := readFile(path, gzipped)
+		if err != nil {
+			return err
+		}
+		res.Files++
+
+		for _, para := range paragraphs(normalize(text, f)) {
+			if isBoilerplate(para, opts.MinWords) {
+				res.Boilerplate++
+				continue
+			}
+			key := sha256.Sum256([]byte(strings.ToLower(para)))
+			if seen[key] {
+				res.Duplicates++
+				continue
+			}
+			seen[key] = true
+			res.Paragraphs = append(res.Paragraphs, para)
+		}
+		return nil
+	})
+	if err != nil {
+		return Result{}, fmt.Errorf("ingest %s: %w", root, err)
+	}
+	return res, nil
+}
+
+// Text joins the paragraphs one per line, the layout bable.ReadCorpus expects.
+func (res Result) Text() string {
+	return strings.Join(res.Paragraphs, "\n") + "\n"
+}
+
+// readFile returns the contents of path, decompressing gzip files.
+func readFile(path string, gzipped bool) (string, error) {
+	file, err := os.Open(path) //nolint:gosec // walking a directory the operator chose
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	var r io.Reader = bufio.NewReader(file)
+	if gzipped {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", path, err)
+		}
+		defer func() {
+			_ = gz.Close()
+		}()
+		r = gz
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", path, err)
+	}
+	return string(data), nil
+}
+
+func normalize(text string, f format) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	switch f {
+	case formatHTML:
+		return stripHTML(text)
+	case formatMarkdown:
+		return stripMarkdown(text)
+	default:
+		return text
+	}
+}
+
+// htmlDropRes match comments and elements whose content is never prose.
+var htmlDropRes = func() []*regexp.Regexp {
+	res := []*regexp.Regexp{regexp.MustCompile(`(?s)<!--.*?-->`)}
+	for _, tag := range []string{
+		"script", "style", "noscript", "nav", "header",
+		"footer", "aside", "form", "svg", "template",
+	} {
+		res = append(res, regexp.MustCompile(`(?is)<`+tag+`\b.*?</`+tag+`\s*>`))
+	}
+	return res
+}()
+
+var (
+	// htmlBlockRe matches tags that end a paragraph.
+	htmlBlockRe = regexp.MustCompile(
+		`(?i)</?(p|div|br|h[1-6]|li|ul|ol|tr|table|section|article|blockquote|pre)\b[^>]*>`)
+	htmlTagRe = regexp.MustCompile(`(?s)<[^>]*>`)
+)
+
+// stripHTML removes tags and non-prose elements and decodes entities,
+// turning block-level elements into paragraph breaks.
+func stripHTML(text string) string {
+	for _, re := range htmlDropRes {
+		text = re.ReplaceAllString(text, " ")
+	}
+	text = htmlBlockRe.ReplaceAllString(text, "\n\n")
+	text = htmlTagRe.ReplaceAllString(text, " ")
+	return html.UnescapeString(text)
+}
+
+var (
+	mdFenceRe    = regexp.MustCompile("(?ms)^\\s*(```|~~~).*?^\\s*(```|~~~)\\s*$")
+	mdImageRe    = regexp.MustCompile(`!\[[^\]]*\]\([^)]*\)`)
+	mdLinkRe     = regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`)
+	mdRefLinkRe  = regexp.MustCompile(`(?m)^\s*\[[^\]]+\]:\s*\S+.*$`)
+	mdHeadingRe  = regexp.MustCompile(`(?m)^\s{0,3}#{1,6}\s+(.*?)\s*#*\s*$`)
+	mdListRe     = regexp.MustCompile(`(?m)^\s*(?:[-*+]|\d+[.)])\s+`)
+	mdQuoteRe    = regexp.MustCompile(`(?m)^\s*>+\s?`)
+	mdRuleRe     = regexp.MustCompile(`(?m)^\s*(?:[-*_]\s*){3,}$`)
+	mdTableRe    = regexp.MustCompile(`(?m)^\s*\|.*\|\s*$`)
+	mdEmphasisRe = regexp.MustCompile("(\\*{1,3}|_{1,3}|`+|~~)([^*_`~\n]+)(\\*{1,3}|_{1,3}|`+|~~)")
+)
+
+// stripMarkdown removes Markdown syntax, keeping the text of links and
+// emphasis. Code blocks, images, tables and rules are dropped entirely.
+// Headings become paragraphs of their own.
+func stripMarkdown(text string) string {
+	text = mdFenceRe.ReplaceAllString(text, "\n")
+	text = mdTableRe.ReplaceAllString(text, "")
+	text = mdImageRe.ReplaceAllString(text, "")
+	text = mdLinkRe.ReplaceAllString(text, "$1")
+	text = mdRefLinkRe.ReplaceAllString(text, "")
+	text = mdHeadingRe.ReplaceAllString(text, "\n$1\n")
+	text = mdRuleRe.ReplaceAllString(text, "\n")
+	text = mdQuoteRe.ReplaceAllString(text, "")
+	text = mdListRe.ReplaceAllString(text, "\n")
+	return mdEmphasisRe.ReplaceAllString(text, "$2")
+}
+
+var blankLineRe = regexp.MustCompile(`\n\s*\n`)
+
+// paragraphs splits text on blank lines and collapses the whitespace
+// inside each paragraph, so wrapped lines are joined back together.
+func paragraphs(text string) []string {
+	parts := blankLineRe.Split(text, -1)
+	paras := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if para := strings.Join(strings.Fields(part), " "); para != "" {
+			paras = append(paras, para)
+		}
+	}
+	return paras
+}
+
+// wordCount counts the words in para, treating every two CJK characters
+// as a word since those scripts don't separate words with spaces.
+func wordCount(para string) int {
+	cjk := 0
+	for _, r := range para {
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana) {
+			cjk++
+		}
+	}
+	return len(strings.Fields(para)) + cjk/2
+}
+
+// boilerplatePhrases appear in site chrome far more often than in prose.
+var boilerplatePhrases = []string{
+	"all rights reserved", "©", "cookie", "privacy policy",
+	"terms of service", "terms of use", "subscribe to", "sign up for",
+	"newsletter", "click here", "read more", "share this", "follow us",
+	"skip to content", "powered by", "javascript",
+}
+
+// chromeWords is the longest a block can be and still be taken for site
+// chrome because of a boilerplate phrase. Longer paragraphs are prose
+// that happens to mention cookies or newsletters.
+const chromeWords = 25
+
+// isBoilerplate reports whether para is site chrome rather than prose:
+// too short, mostly non-letters, or a short block containing a typical
+// boilerplate phrase.
+func isBoilerplate(para string, minWords int) bool {
+	words := wordCount(para)
+	if words < minWords {
+		return true
+	}
+
+	letters, total := 0, 0
+	for _, r := range para {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		total++
+		if unicode.IsLetter(r) {
+			letters++
+		}
+	}
+	if total == 0 || letters*10 < total*7 {
+		return true
+	}
+
+	if words > chromeWords {
+		return false
+	}
+	lower := strings.ToLower(para)
+	for _, phrase := range boilerplatePhrases {
+		if strings.Contains(lower, phrase) {
+			return true
+		}
+	}
+	return false
+}
@@ -9,6 +9,43 @@ import (
 // Config contains the settings found inside the toml file.
 type Config struct {
 	StreamInterval float64
+	// StreamSeconds is how long the body of a single page keeps streaming
+	// before the page is finished off. Defaults to two minutes.
+	StreamSeconds float64
+	// StreamBytes caps how much body text a single page streams.
+	// Zero leaves only the time budget.
+	StreamBytes int
+	// SessionStreamSeconds and SessionStreamBytes cap the streaming of
+	// all pages of one IP session together. Zero leaves them uncapped.
+	SessionStreamSeconds float64
+	SessionStreamBytes   int
+	// InlineLinkDensity is the share of noun-like words in the streamed
+	// body that become links into the maze. Zero disables inline links.
+	InlineLinkDensity float64
+	// SiteSecret seeds page generation, so the same URL always renders
+	// the same page without the output being predictable from outside.
+	SiteSecret string
+	// SnapshotDir holds prebuilt chain-<lang>.snap files that are
+	// loaded at startup instead of tokenizing the manifest.
+	SnapshotDir string
+	// Corpora maps a language tag to its corpus file. Requests pick one
+	// through Accept-Language. Defaults to the English manifest.
+	Corpora map[string]string
+	// CorpusWatchInterval is how often, in seconds, corpus and snapshot
+	// files are checked for changes. Zero disables watching; SIGHUP
+	// always triggers a reload.
+	CorpusWatchInterval float64
+	// MaxOrder is the longest prefix, in words, the chains store.
+	MaxOrder int
+	// Creativity tunes backoff between chain orders.
+	Creativity Creativity
+	// Generator is the default text backend, see bable.GeneratorNames.
+	Generator string
+	// AdminToken guards the operator endpoints such as chain statistics.
+	// They are only mounted when it is set.
+	AdminToken string
+	// Policies override settings for matching hosts or paths.
+	Policies []Policy `toml:"Policy"`
 }
 
 // Conf contains the setting.
@@ -31,3 +68,20 @@ func LoadConfig(path string) (Config, error) {
 	Conf = c
 	return Conf, nil
 }
+
+// Creativity tunes how far generated text strays from the corpus.
+// Long prefixes reproduce the source almost verbatim, while short ones
+// drift into nonsense; these settings decide when to trade one for the other.
+type Creativity struct {
+	// MinSuccessors is how many distinct successors a prefix needs before
+	// it is used. Sparser prefixes back off to a shorter one, so higher
+	// values copy less of the source.
+	MinSuccessors int
+	// Jump is the chance, per word, of starting one order lower than
+	// requested even when the longer prefix would do.
+	Jump float64
+	// Focus is how many times likelier a topic word is picked than the
+	// corpus suggests. Words leading to the topic get part of the boost.
+	// One or less disables steering.
+	Focus float64
+}
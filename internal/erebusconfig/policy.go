@@ -0,0 +1,62 @@
+package erebusconfig
+
+import "strings"
+
+// Policy holds per-host or per-route overrides.
+// An empty Host or PathPrefix matches every request.
+type Policy struct {
+	Host       string
+	PathPrefix string
+	Generator  string
+	// Degrade is off unless the matched policy enables it.
+	Degrade Degrade
+}
+
+// Degrade sets the intensity of each filter applied to streamed text to
+// make it worthless as training data while it still renders normally.
+// Intensities are the share of words, or of sentences for Shuffle, that
+// a filter touches; zero switches the filter off.
+type Degrade struct {
+	// Homoglyphs swaps a Latin letter for a look-alike from another script.
+	Homoglyphs float64
+	// ZeroWidth hides a zero-width space inside a word.
+	ZeroWidth float64
+	// Misspell swaps, drops or doubles a letter.
+	Misspell float64
+	// Shuffle moves sentences out of order.
+	Shuffle float64
+}
+
+// PolicyFor returns the effective policy for a request to host and path.
+// The most specific matching policy wins: a host match outranks a path
+// match, and longer path prefixes outrank shorter ones. Fields left empty
+// in the matched policy fall back to the top-level settings.
+func (c Config) PolicyFor(host, path string) Policy {
+	effective := Policy{Host: host, Generator: c.Generator}
+
+	best := -1
+	for _, p := range c.Policies {
+		if p.Host != "" && !strings.EqualFold(p.Host, host) {
+			continue
+		}
+		if !strings.HasPrefix(path, p.PathPrefix) {
+			continue
+		}
+		score := len(p.PathPrefix)
+		if p.Host != "" {
+			score += 1 << 16
+		}
+		if score <= best {
+			continue
+		}
+		best = score
+		effective.PathPrefix = p.PathPrefix
+		if p.Generator != "" {
+			effective.Generator = p.Generator
+		} else {
+			effective.Generator = c.Generator
+		}
+		effective.Degrade = p.Degrade
+	}
+	return effective
+}
@@ -0,0 +1,193 @@
+package pages
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"iter"
+	"strings"
+)
+
+// bodyToken is one unit of the streamed article body: a word, or markup
+// opening or closing an element.
+type bodyToken struct {
+	word string
+	// glue writes word without a space in front, for punctuation split
+	// off a linked word.
+	glue bool
+	// tag is the element to open, with attrs as its raw attributes.
+	tag   string
+	attrs string
+	// closing closes the innermost open element.
+	closing bool
+}
+
+func wordToken(word string) bodyToken {
+	return bodyToken{word: word}
+}
+
+func openToken(tag, attrs string) bodyToken {
+	return bodyToken{tag: tag, attrs: attrs}
+}
+
+func closeToken() bodyToken {
+	return bodyToken{closing: true}
+}
+
+// inlineTags are elements that sit inside running text, so the words
+// around them are separated by spaces.
+var inlineTags = map[string]bool{"a": true, "em": true, "strong": true}
+
+// bodyWriter writes body tokens as HTML, keeping a stack of the elements
+// it opened so that Close can always leave the markup well-formed, however
+// early the stream ends.
+type bodyWriter struct {
+	w    io.Writer
+	open []string
+	// written counts the bytes written so far.
+	written int
+	// space is set when the next word needs a space in front of it.
+	space bool
+}
+
+func newBodyWriter(w io.Writer) *bodyWriter {
+	return &bodyWriter{w: w}
+}
+
+// write writes token, escaping words.
+func (bw *bodyWriter) write(token bodyToken) error {
+	switch {
+	case token.closing:
+		return bw.closeTag()
+	case token.tag != "":
+		return bw.openTag(token.tag, token.attrs)
+	default:
+		if token.glue {
+			bw.space = false
+		}
+		return bw.word(token.word)
+	}
+}
+
+func (bw *bodyWriter) word(word string) error {
+	if bw.space {
+		word = " " + word
+	}
+	bw.space = true
+	return bw.raw(html.EscapeString(word))
+}
+
+func (bw *bodyWriter) openTag(tag, attrs string) error {
+	var b strings.Builder
+	if inlineTags[tag] && bw.space {
+		b.WriteByte(' ')
+	}
+	b.WriteString("<" + tag)
+	if attrs != "" {
+		b.WriteString(" " + attrs)
+	}
+	b.WriteByte('>')
+	bw.open = append(bw.open, tag)
+	bw.space = false
+	return bw.raw(b.String())
+}
+
+func (bw *bodyWriter) closeTag() error {
+	if len(bw.open) == 0 {
+		return nil
+	}
+	tag := bw.open[len(bw.open)-1]
+	bw.open = bw.open[:len(bw.open)-1]
+	bw.space = inlineTags[tag]
+	return bw.raw("</" + tag + ">")
+}
+
+func (bw *bodyWriter) raw(s string) error {
+	n, err := io.WriteString(bw.w, s)
+	bw.written += n
+	return err
+}
+
+// Close closes every element that is still open.
+func (bw *bodyWriter) Close() error {
+	for len(bw.open) > 0 {
+		if err := bw.closeTag(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// structureBody lays sentences out as an article: paragraphs of a few
+// sentences, with the occasional subheading, blockquote and inline link
+// into the maze. It draws from p's RNG, so p should be a fork of the page
+// when the length of the body varies.
+func (p *Page) structureBody(sentences iter.Seq[string]) iter.Seq[bodyToken] {
+	return func(yield func(bodyToken) bool) {
+		next, stop := iter.Pull(sentences)
+		defer stop()
+
+		words := func(text string) bool {
+			for _, word := range strings.Fields(text) {
+				if !yield(wordToken(word)) {
+					return false
+				}
+			}
+			return true
+		}
+		// block writes count sentences inside the given elements and
+		// reports whether the stream should go on.
+		block := func(count int, tags ...string) bool {
+			for _, tag := range tags {
+				if !yield(openToken(tag, "")) {
+					return false
+				}
+			}
+			more := true
+			for range count {
+				sentence, ok := next()
+				if !ok {
+					more = false
+					break
+				}
+				if !words(sentence) {
+					return false
+				}
+			}
+			// 25% chance of a paragraph ending in a link
+			if more && tags[len(tags)-1] == "p" && p.rng.Float32() < 0.25 {
+				link := p.generateOneLink()
+				if !yield(openToken("a", fmt.Sprintf(`href="%s"`, html.EscapeString(link.URL)))) ||
+					!words(link.Text) || !yield(closeToken()) {
+					return false
+				}
+			}
+			for range tags {
+				if !yield(closeToken()) {
+					return false
+				}
+			}
+			return more
+		}
+
+		if !block(2+p.rng.IntN(3), "p") {
+			return
+		}
+		for {
+			r := p.rng.Float32()
+			switch {
+			case r < 0.12:
+				if !yield(openToken("h3", "")) || !words(p.gen.Title(p.rng, 6)) || !yield(closeToken()) {
+					return
+				}
+			case r < 0.22:
+				if !block(1+p.rng.IntN(2), "blockquote", "p") {
+					return
+				}
+			}
+			if !block(2+p.rng.IntN(5), "p") {
+				return
+			}
+		}
+	}
+}
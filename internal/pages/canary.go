@@ -0,0 +1,68 @@
+package pages
+
+import (
+	"iter"
+	"math/rand/v2"
+	"strings"
+	"unicode"
+
+	"Erebus/internal/bable"
+)
+
+// weaveCanary swaps a word of every few sentences in seq for the session's
+// canary, starting with the first one so even short visits receive it.
+// Placement uses the global source: the canary differs per session anyway,
+// and the page RNG must yield the same text for every visitor.
+func weaveCanary(seq iter.Seq[string], canary string) iter.Seq[string] {
+	if canary == "" {
+		return seq
+	}
+	return func(yield func(string) bool) {
+		gap := 0
+		for sentence := range seq {
+			if gap == 0 {
+				if woven, ok := replaceWord(sentence, canary); ok {
+					sentence = woven
+					gap = 4 + rand.IntN(8) //nolint:gosec
+				}
+			} else {
+				gap--
+			}
+			if !yield(sentence) {
+				return
+			}
+		}
+	}
+}
+
+// replaceWord replaces a random content word of sentence with word,
+// keeping the punctuation attached to it. The first word is never picked,
+// so the canary doesn't stand out capitalised.
+func replaceWord(sentence, word string) (string, bool) {
+	fields := strings.Fields(sentence)
+	var candidates []int
+	for i, field := range fields[min(1, len(fields)):] {
+		bare := strings.TrimRightFunc(field, unicode.IsPunct)
+		if len(bare) >= 4 && isLowerWord(bare) && !bable.IsStopWord(bare) {
+			candidates = append(candidates, i+1)
+		}
+	}
+	if len(candidates) == 0 {
+		return sentence, false
+	}
+
+	i := candidates[rand.IntN(len(candidates))] //nolint:gosec
+	bare := strings.TrimRightFunc(fields[i], unicode.IsPunct)
+	fields[i] = word + fields[i][len(bare):]
+	return strings.Join(fields, " "), true
+}
+
+// isLowerWord reports whether s consists of lower-case letters only.
+func isLowerWord(s string) bool {
+	for _, r := range s {
+		if !unicode.IsLower(r) {
+			return false
+		}
+	}
+	return true
+}
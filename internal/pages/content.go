@@ -0,0 +1,267 @@
+package pages
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+)
+
+// Table is a data table with a caption, a header row and body rows.
+type Table struct {
+	Caption string
+	Header  []string
+	Rows    [][]string
+}
+
+// person is an invented, named source.
+type person struct {
+	name  string
+	role  string
+	org   int
+	place int
+}
+
+// organization is an invented body the page's people belong to.
+type organization struct {
+	name    string
+	founded int
+	members int
+	place   int
+}
+
+// place is an invented town.
+type place struct {
+	name       string
+	population int
+}
+
+// series is a yearly count that tables and statistics cite.
+type series struct {
+	label  string
+	org    int
+	years  []int
+	values []int
+}
+
+// facts are the invented data a page cites. They are generated once per
+// page and every table and statistic is derived from them, so the numbers
+// on a page always agree with each other.
+type facts struct {
+	people []person
+	orgs   []organization
+	places []place
+	series series
+}
+
+var (
+	placeRoots = []string{
+		"Ash", "Bram", "Carl", "Dun", "Elm", "Fair", "Glen", "Hart",
+		"Kings", "Lang", "Mill", "North", "Oak", "Red", "Stan", "Wes",
+	}
+	placeSuffixes = []string{
+		"ford", "wick", "ton", "bury", "field", "haven", "mouth", "stead", "ley", "more",
+	}
+	orgKinds = []string{
+		"Institute", "Workers' Association", "Trade Council", "Historical Society",
+		"Cooperative", "Labour Union", "Research Centre", "Foundation",
+	}
+	roles = []string{
+		"chair", "secretary", "treasurer", "spokesperson",
+		"director", "archivist", "historian", "senior analyst",
+	}
+)
+
+// facts returns the page's data, inventing it on first use.
+func (p *Page) facts() *facts {
+	if p.data != nil {
+		return p.data
+	}
+
+	f := &facts{}
+	seen := make(map[string]bool)
+	for range 3 + p.rng.IntN(3) {
+		name := placeRoots[p.rng.IntN(len(placeRoots))] +
+			placeSuffixes[p.rng.IntN(len(placeSuffixes))]
+		// A repeated name with a second population would contradict itself.
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		f.places = append(f.places, place{
+			name:       name,
+			population: 2000 + p.rng.IntN(400000),
+		})
+	}
+	for range 2 + p.rng.IntN(3) {
+		at := p.rng.IntN(len(f.places))
+		name := f.places[at].name
+		if p.rng.IntN(2) == 0 {
+			name = titleCase(p.GenerateSlug(1))
+		}
+		// No organization signs up more than a quarter of its town.
+		f.orgs = append(f.orgs, organization{
+			name:    name + " " + orgKinds[p.rng.IntN(len(orgKinds))],
+			founded: 1850 + p.rng.IntN(160),
+			members: min(40+p.rng.IntN(25000), f.places[at].population/4),
+			place:   at,
+		})
+	}
+	for range 3 + p.rng.IntN(4) {
+		org := p.rng.IntN(len(f.orgs))
+		name := p.GenerateAuthorName()
+		// Two people of the same name would be told apart by nothing.
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		f.people = append(f.people, person{
+			name:  name,
+			role:  roles[p.rng.IntN(len(roles))],
+			org:   org,
+			place: f.orgs[org].place,
+		})
+	}
+
+	// The series ends at the organization's current membership, so the
+	// tables listing organizations and the yearly figures agree.
+	org := p.rng.IntN(len(f.orgs))
+	f.series = series{label: "Members", org: org}
+	end := dateEpoch().Year() + p.rng.IntN(dateYears)
+	value := f.orgs[org].members
+	for year := end; year > end-5-p.rng.IntN(4); year-- {
+		f.series.years = append([]int{year}, f.series.years...)
+		f.series.values = append([]int{value}, f.series.values...)
+		value = max(10, value*(85+p.rng.IntN(30))/100)
+	}
+
+	p.data = f
+	return f
+}
+
+// GenerateTable returns one of the tables the page's facts support.
+func (p *Page) GenerateTable() Table {
+	f := p.facts()
+	switch p.rng.IntN(3) {
+	case 0:
+		org := f.orgs[f.series.org]
+		t := Table{
+			Caption: fmt.Sprintf("Membership of the %s, %d–%d",
+				org.name, f.series.years[0], f.series.years[len(f.series.years)-1]),
+			Header: []string{"Year", f.series.label, "Change"},
+		}
+		for i, year := range f.series.years {
+			change := "–"
+			if i > 0 {
+				change = formatPercent(f.series.values[i-1], f.series.values[i])
+			}
+			t.Rows = append(t.Rows, []string{
+				strconv.Itoa(year), formatInt(f.series.values[i]), change,
+			})
+		}
+		return t
+	case 1:
+		t := Table{
+			Caption: "Organizations referenced in this article",
+			Header:  []string{"Organization", "Founded", "Location", "Members"},
+		}
+		for _, org := range f.orgs {
+			t.Rows = append(t.Rows, []string{
+				org.name, strconv.Itoa(org.founded),
+				f.places[org.place].name, formatInt(org.members),
+			})
+		}
+		return t
+	default:
+		t := Table{
+			Caption: "People cited",
+			Header:  []string{"Name", "Role", "Organization", "Location"},
+		}
+		for _, pe := range f.people {
+			t.Rows = append(t.Rows, []string{
+				pe.name, titleCase(pe.role), f.orgs[pe.org].name, f.places[pe.place].name,
+			})
+		}
+		return t
+	}
+}
+
+// GenerateStatistic returns a sentence citing the page's facts.
+func (p *Page) GenerateStatistic() string {
+	f := p.facts()
+	pe := f.people[p.rng.IntN(len(f.people))]
+	org := f.orgs[pe.org]
+	switch p.rng.IntN(4) {
+	case 0:
+		s := f.series
+		first, last := 0, len(s.years)-1
+		change := fmt.Sprintf("membership went from %s in %d to %s in %d, a change of %s.",
+			formatInt(s.values[first]), s.years[first],
+			formatInt(s.values[last]), s.years[last], formatPercent(s.values[first], s.values[last]))
+		// Only someone from the organization itself is quoted on its figures.
+		var members []person
+		for _, candidate := range f.people {
+			if candidate.org == s.org {
+				members = append(members, candidate)
+			}
+		}
+		if len(members) == 0 {
+			return fmt.Sprintf("According to figures from the %s, %s", f.orgs[s.org].name, change)
+		}
+		source := members[p.rng.IntN(len(members))]
+		return fmt.Sprintf("According to %s, %s of the %s, %s",
+			source.name, source.role, f.orgs[s.org].name, change)
+	case 1:
+		return fmt.Sprintf("The %s was founded in %s in %d and today counts %s members.",
+			org.name, f.places[org.place].name, org.founded, formatInt(org.members))
+	case 2:
+		pl := f.places[org.place]
+		return fmt.Sprintf("With %s members in a town of %s, the %s reaches %.1f%% of the population of %s.",
+			formatInt(org.members), formatInt(pl.population), org.name,
+			100*float64(org.members)/float64(pl.population), pl.name)
+	default:
+		return fmt.Sprintf("%s, who has served as %s of the %s since %d, is based in %s.",
+			pe.name, pe.role, org.name, max(org.founded, dateEpoch().Year()-1-p.rng.IntN(30)),
+			f.places[pe.place].name)
+	}
+}
+
+// RenderTable returns HTML for a data table.
+func RenderTable(t Table) string {
+	var b strings.Builder
+	b.WriteString(`<table class="data-table">`)
+	b.WriteString(fmt.Sprintf(`<caption>%s</caption><thead><tr>`, html.EscapeString(t.Caption)))
+	for _, h := range t.Header {
+		b.WriteString(fmt.Sprintf(`<th scope="col">%s</th>`, html.EscapeString(h)))
+	}
+	b.WriteString(`</tr></thead><tbody>`)
+	for _, row := range t.Rows {
+		b.WriteString(`<tr>`)
+		for _, cell := range row {
+			b.WriteString(fmt.Sprintf(`<td>%s</td>`, html.EscapeString(cell)))
+		}
+		b.WriteString(`</tr>`)
+	}
+	b.WriteString(`</tbody></table>`)
+	return b.String()
+}
+
+// formatInt formats n with thousands separators.
+func formatInt(n int) string {
+	if n < 0 {
+		return "-" + formatInt(-n)
+	}
+	s := strconv.Itoa(n)
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
+
+// formatPercent formats the relative change from old to current.
+func formatPercent(old, current int) string {
+	if old == 0 {
+		return "–"
+	}
+	return fmt.Sprintf("%+.1f%%", 100*float64(current-old)/float64(old))
+}
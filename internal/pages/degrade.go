@@ -0,0 +1,154 @@
+package pages
+
+import (
+	"iter"
+	"math/rand/v2"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"Erebus/internal/erebusconfig"
+)
+
+// homoglyphs maps Latin letters to look-alikes from Cyrillic and Greek.
+var homoglyphs = map[rune]rune{
+	'a': 'а', 'c': 'с', 'e': 'е', 'i': 'і', 'j': 'ј', 'o': 'о', 'p': 'р',
+	's': 'ѕ', 'x': 'х', 'y': 'у', 'A': 'А', 'B': 'В', 'C': 'С', 'E': 'Е',
+	'H': 'Н', 'I': 'І', 'K': 'К', 'M': 'М', 'N': 'Ν', 'O': 'О', 'P': 'Р',
+	'T': 'Т', 'X': 'Х', 'Y': 'Υ', 'Z': 'Ζ',
+}
+
+// zeroWidthSpace renders as nothing but splits the word for tokenizers.
+const zeroWidthSpace = "\u200b"
+
+// shuffleWindow is how many sentences are buffered so shuffled sentences
+// can be moved back in the stream.
+const shuffleWindow = 8
+
+// degrade applies the filters enabled in d to seq. Filters with zero
+// intensity are skipped entirely.
+func degrade(seq iter.Seq[string], rng *rand.Rand, d erebusconfig.Degrade) iter.Seq[string] {
+	if d.Shuffle > 0 {
+		seq = shuffleSentences(seq, rng, d.Shuffle)
+	}
+	if d.Homoglyphs <= 0 && d.ZeroWidth <= 0 && d.Misspell <= 0 {
+		return seq
+	}
+	return func(yield func(string) bool) {
+		for sentence := range seq {
+			words := strings.Fields(sentence)
+			for i, word := range words {
+				if d.Misspell > 0 && rng.Float64() < d.Misspell {
+					word = misspell(rng, word)
+				}
+				if d.Homoglyphs > 0 && rng.Float64() < d.Homoglyphs {
+					word = swapHomoglyph(rng, word)
+				}
+				if d.ZeroWidth > 0 && rng.Float64() < d.ZeroWidth {
+					word = insertZeroWidth(rng, word)
+				}
+				words[i] = word
+			}
+			if !yield(strings.Join(words, " ")) {
+				return
+			}
+		}
+	}
+}
+
+// shuffleSentences holds sentences back in a small window and releases
+// a random one instead of the oldest with probability intensity.
+func shuffleSentences(seq iter.Seq[string], rng *rand.Rand, intensity float64) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		var window []string
+		emit := func() bool {
+			i := 0
+			if rng.Float64() < intensity {
+				i = rng.IntN(len(window))
+			}
+			sentence := window[i]
+			window = append(window[:i], window[i+1:]...)
+			return yield(sentence)
+		}
+		for sentence := range seq {
+			window = append(window, sentence)
+			if len(window) < shuffleWindow {
+				continue
+			}
+			if !emit() {
+				return
+			}
+		}
+		for len(window) > 0 {
+			if !emit() {
+				return
+			}
+		}
+	}
+}
+
+// letterPositions returns the byte offsets of the letters in word.
+func letterPositions(word string) []int {
+	var positions []int
+	for i, r := range word {
+		if unicode.IsLetter(r) {
+			positions = append(positions, i)
+		}
+	}
+	return positions
+}
+
+// misspell swaps two neighbouring letters, drops one or doubles one.
+// Words shorter than four letters are left alone so they stay readable.
+func misspell(rng *rand.Rand, word string) string {
+	runes := []rune(word)
+	var letters []int
+	for i, r := range runes {
+		if unicode.IsLetter(r) {
+			letters = append(letters, i)
+		}
+	}
+	if len(letters) < 4 {
+		return word
+	}
+
+	// Keep the first letter, readers skim past errors after it.
+	i := letters[1+rng.IntN(len(letters)-2)]
+	switch rng.IntN(3) {
+	case 0:
+		if unicode.IsLetter(runes[i+1]) {
+			runes[i], runes[i+1] = runes[i+1], runes[i]
+		}
+	case 1:
+		runes = append(runes[:i], runes[i+1:]...)
+	default:
+		runes = append(runes[:i+1], runes[i:]...)
+	}
+	return string(runes)
+}
+
+// swapHomoglyph replaces one letter of word that has a look-alike.
+func swapHomoglyph(rng *rand.Rand, word string) string {
+	var candidates []int
+	for i, r := range word {
+		if _, ok := homoglyphs[r]; ok {
+			candidates = append(candidates, i)
+		}
+	}
+	if len(candidates) == 0 {
+		return word
+	}
+	i := candidates[rng.IntN(len(candidates))]
+	r, size := utf8.DecodeRuneInString(word[i:])
+	return word[:i] + string(homoglyphs[r]) + word[i+size:]
+}
+
+// insertZeroWidth puts a zero-width space between two letters of word.
+func insertZeroWidth(rng *rand.Rand, word string) string {
+	positions := letterPositions(word)
+	if len(positions) < 2 {
+		return word
+	}
+	i := positions[1+rng.IntN(len(positions)-1)]
+	return word[:i] + zeroWidthSpace + word[i:]
+}
@@ -0,0 +1,335 @@
+package pages
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// feedFormat is the syndication format a feed is served in.
+type feedFormat int
+
+const (
+	rssFeed feedFormat = iota
+	atomFeed
+)
+
+// feedNames maps the last path segment of a feed URL to its format.
+var feedNames = map[string]feedFormat{
+	"feed": rssFeed, "rss.xml": rssFeed, "atom.xml": atomFeed,
+}
+
+// contentType returns the Content-Type header for ff.
+func (ff feedFormat) contentType() string {
+	if ff == atomFeed {
+		return "application/atom+xml; charset=utf-8"
+	}
+	return "application/rss+xml; charset=utf-8"
+}
+
+// mediaType returns the media type of ff without parameters.
+func (ff feedFormat) mediaType() string {
+	typ, _, _ := strings.Cut(ff.contentType(), ";")
+	return typ
+}
+
+// parseFeed recognizes the feed of the whole site, such as /feed, and the
+// feeds of listing pages, such as /news/feed, /tag/x/rss.xml or
+// /author/x/atom.xml. It returns the format and the path of the page the
+// feed belongs to. A feed path under an article is not a feed.
+func parseFeed(path string) (feedFormat, string, bool) {
+	base, last := "", strings.Trim(path, "/")
+	if i := strings.LastIndexByte(last, '/'); i >= 0 {
+		base, last = last[:i], last[i+1:]
+	}
+	ff, ok := feedNames[last]
+	if !ok {
+		return 0, "", false
+	}
+	base = "/" + base
+	if base != "/" && parseRoute(base).kind == articlePage {
+		return 0, "", false
+	}
+	return ff, base, true
+}
+
+// feedPath returns the path of the feed advertised on the page at path,
+// and its media type. Listings have their own feed; articles in a
+// category point to its feed and everything else to the site's.
+func feedPath(path string) (string, string) {
+	rt := parseRoute(path)
+	switch rt.kind {
+	case tagPage:
+		return fmt.Sprintf("/tag/%s/rss.xml", rt.name), "application/rss+xml"
+	case authorPage:
+		return fmt.Sprintf("/author/%s/atom.xml", rt.name), "application/atom+xml"
+	case categoryPage:
+		return fmt.Sprintf("/%s/feed", rt.name), "application/rss+xml"
+	case articlePage:
+		cat, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+		if slices.Contains(categories, cat) {
+			return fmt.Sprintf("/%s/feed", cat), "application/rss+xml"
+		}
+	}
+	return "/feed", "application/rss+xml"
+}
+
+// renderFeedLink advertises the feed of the page at path. The feed path
+// is built from the decoded request path, so it is escaped.
+func renderFeedLink(path string) string {
+	href, typ := feedPath(path)
+	_, base, _ := parseFeed(href)
+	return fmt.Sprintf("\n    <link rel=\"alternate\" type=\"%s\" title=\"%s\" href=\"%s\">",
+		typ, html.EscapeString(feedTitle(base)), html.EscapeString(escapePath(href)))
+}
+
+// feedTitle returns the title of the feed of the page at base.
+func feedTitle(base string) string {
+	if base == "/" {
+		return "Latest posts"
+	}
+	return parseRoute(base).title()
+}
+
+// feedEntry is one post in a feed.
+type feedEntry struct {
+	Link
+	summary  string
+	author   string
+	category string
+	date     time.Time
+}
+
+// feed is a generated feed, ready to be encoded in either format.
+type feed struct {
+	title       string
+	description string
+	// link is the page the feed belongs to and self the feed itself, both
+	// absolute.
+	link    string
+	self    string
+	author  string
+	entries []feedEntry
+}
+
+// generateFeed invents the feed of the page at base: a title and
+// description fitting the page and a run of entries, newest first, with
+// generated links and summaries. self is the escaped path and query of
+// the feed itself.
+func (p *Page) generateFeed(origin, base, self string) feed {
+	rt := route{kind: articlePage}
+	description := "The latest posts from every section."
+	if base != "/" {
+		rt = parseRoute(base)
+		description = p.introduction(rt)
+	}
+	f := feed{
+		title:       feedTitle(base),
+		description: description,
+		link:        origin + escapePath(base),
+		self:        origin + self,
+	}
+	category := ""
+	switch rt.kind {
+	case authorPage:
+		f.author = authorName(rt.name)
+	case categoryPage:
+		category = rt.name
+	}
+
+	// The entries are dated like the first page of the listing at base.
+	for _, date := range p.listingDates(rt, pageCount(base, rt.kind), 10+p.rng.IntN(11)) {
+		entry := feedEntry{
+			Link:     p.generateOneLink(),
+			summary:  p.gen.Sentences(p.rng, 2+p.rng.IntN(2)),
+			author:   f.author,
+			category: category,
+			date:     date,
+		}
+		entry.URL = origin + entry.URL
+		if entry.author == "" {
+			entry.author = p.GenerateAuthorName()
+		}
+		if entry.category == "" {
+			entry.category = p.RandomCategory()
+		}
+		f.entries = append(f.entries, entry)
+	}
+	return f
+}
+
+type rssDocument struct {
+	XMLName   xml.Name   `xml:"rss"`
+	Version   string     `xml:"version,attr"`
+	XMLNSAtom string     `xml:"xmlns:atom,attr"`
+	XMLNSDC   string     `xml:"xmlns:dc,attr"`
+	Channel   rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Language      string    `xml:"language"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Self          atomLink  `xml:"atom:link"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	Description string  `xml:"description"`
+	Creator     string  `xml:"dc:creator"`
+	Category    string  `xml:"category"`
+	PubDate     string  `xml:"pubDate"`
+	GUID        rssGUID `xml:"guid"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type atomDocument struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Lang    string      `xml:"xml:lang,attr"`
+	Title   string      `xml:"title"`
+	Sub     string      `xml:"subtitle"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Author  *atomPerson `xml:"author,omitempty"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	Title     string       `xml:"title"`
+	Link      atomLink     `xml:"link"`
+	ID        string       `xml:"id"`
+	Published string       `xml:"published"`
+	Updated   string       `xml:"updated"`
+	Author    atomPerson   `xml:"author"`
+	Category  atomCategory `xml:"category"`
+	Summary   string       `xml:"summary"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+// rss returns f as an RSS 2.0 document.
+func (f feed) rss(lang string) rssDocument {
+	doc := rssDocument{
+		Version:   "2.0",
+		XMLNSAtom: "http://www.w3.org/2005/Atom",
+		XMLNSDC:   "http://purl.org/dc/elements/1.1/",
+		Channel: rssChannel{
+			Title:       f.title,
+			Link:        f.link,
+			Description: f.description,
+			Language:    lang,
+			Self:        atomLink{Href: f.self, Rel: "self", Type: rssFeed.mediaType()},
+		},
+	}
+	for _, e := range f.entries {
+		doc.Channel.Items = append(doc.Channel.Items, rssItem{
+			Title:       e.Text,
+			Link:        e.URL,
+			Description: e.summary,
+			Creator:     e.author,
+			Category:    e.category,
+			PubDate:     e.date.Format(time.RFC1123Z),
+			GUID:        rssGUID{IsPermaLink: true, Value: e.URL},
+		})
+	}
+	if len(f.entries) > 0 {
+		doc.Channel.LastBuildDate = f.entries[0].date.Format(time.RFC1123Z)
+	}
+	return doc
+}
+
+// atom returns f as an Atom document.
+func (f feed) atom(lang string) atomDocument {
+	doc := atomDocument{
+		Lang:  lang,
+		Title: f.title,
+		Sub:   f.description,
+		ID:    f.self,
+		Links: []atomLink{
+			{Href: f.self, Rel: "self", Type: atomFeed.mediaType()},
+			{Href: f.link, Rel: "alternate", Type: "text/html"},
+		},
+	}
+	if f.author != "" {
+		doc.Author = &atomPerson{Name: f.author}
+	}
+	for _, e := range f.entries {
+		date := e.date.Format(time.RFC3339)
+		doc.Entries = append(doc.Entries, atomEntry{
+			Title:     e.Text,
+			Link:      atomLink{Href: e.URL, Rel: "alternate", Type: "text/html"},
+			ID:        e.URL,
+			Published: date,
+			Updated:   date,
+			Author:    atomPerson{Name: e.author},
+			Category:  atomCategory{Term: e.category},
+			Summary:   e.summary,
+		})
+	}
+	if len(f.entries) > 0 {
+		doc.Updated = f.entries[0].date.Format(time.RFC3339)
+	}
+	return doc
+}
+
+// serveFeed writes the ff feed of the page at base. Like a page, the feed
+// is seeded from its own URL, so refetching it returns the same entries.
+func serveFeed(w http.ResponseWriter, r *http.Request, ff feedFormat, base string) {
+	page := NewPage(r)
+	self := r.URL.EscapedPath()
+	if r.URL.RawQuery != "" {
+		self += "?" + r.URL.RawQuery
+	}
+	f := page.generateFeed(requestOrigin(r), base, self)
+
+	var doc any = f.rss(page.lang)
+	if ff == atomFeed {
+		doc = f.atom(page.lang)
+	}
+
+	w.Header().Set("Content-Type", ff.contentType())
+	w.Header().Set("Content-Language", page.lang)
+	if _, err := w.Write([]byte(xml.Header)); err != nil {
+		return
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		log.Printf("error encoding feed: %s", err.Error())
+	}
+}
+
+// requestOrigin returns the scheme and host r was made to.
+func requestOrigin(r *http.Request) string {
+	scheme := "https"
+	if r.TLS == nil {
+		scheme = "http"
+	}
+	return fmt.Sprintf("%s://%s", scheme, r.Host)
+}
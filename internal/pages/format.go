@@ -0,0 +1,378 @@
+package pages
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// format is a representation a page can be served in.
+type format int
+
+const (
+	formatHTML format = iota
+	formatAMP
+	formatMarkdown
+	formatJSON
+	formatText
+)
+
+// formatNames maps ?format= values to formats.
+var formatNames = map[string]format{
+	"html": formatHTML, "amp": formatAMP,
+	"markdown": formatMarkdown, "md": formatMarkdown,
+	"json": formatJSON,
+	"text": formatText, "txt": formatText, "plain": formatText,
+}
+
+// formatSuffixes maps path extensions to formats. The canonical path is
+// the path without the extension.
+var formatSuffixes = map[string]format{
+	".md": formatMarkdown, ".markdown": formatMarkdown,
+	".json": formatJSON,
+	".txt":  formatText,
+}
+
+// formatTypes maps media types in Accept headers to formats.
+var formatTypes = map[string]format{
+	"text/html": formatHTML, "application/xhtml+xml": formatHTML,
+	"text/markdown": formatMarkdown, "text/x-markdown": formatMarkdown,
+	"application/json": formatJSON, "application/ld+json": formatJSON,
+	"text/plain": formatText,
+}
+
+// contentType returns the Content-Type header for f.
+func (f format) contentType() string {
+	switch f {
+	case formatMarkdown:
+		return "text/markdown; charset=utf-8"
+	case formatJSON:
+		return "application/json; charset=utf-8"
+	case formatText:
+		return "text/plain; charset=utf-8"
+	}
+	return "text/html; charset=utf-8"
+}
+
+// negotiateFormat returns the representation r asks for and the URL of
+// the page it represents. An explicit ?format=, a /amp/ prefix or a
+// suffix such as .md wins over the Accept header. The returned URL has
+// those stripped, so every representation renders the same page.
+func negotiateFormat(r *http.Request) (format, *url.URL) {
+	canonical := *r.URL
+	f, explicit := formatHTML, false
+
+	query := canonical.Query()
+	if name := query.Get("format"); name != "" {
+		if named, ok := formatNames[strings.ToLower(name)]; ok {
+			f, explicit = named, true
+		}
+		query.Del("format")
+		canonical.RawQuery = query.Encode()
+	}
+
+	p := canonical.Path
+	if p == "/amp" {
+		// The AMP homepage, which the prefix below doesn't match.
+		p = "/amp/"
+	}
+	if rest, ok := strings.CutPrefix(p, "/amp/"); ok {
+		p = "/" + rest
+		if !explicit {
+			f, explicit = formatAMP, true
+		}
+	}
+	if suffixed, ok := formatSuffixes[path.Ext(p)]; ok {
+		p = strings.TrimSuffix(p, path.Ext(p))
+		if p == "/index" {
+			p = "/"
+		}
+		if !explicit {
+			f, explicit = suffixed, true
+		}
+	}
+	canonical.Path, canonical.RawPath = p, ""
+
+	if !explicit {
+		// Accept has the same syntax as Accept-Language.
+		for _, mr := range parseAcceptLanguage(r.Header.Get("Accept")) {
+			if accepted, ok := formatTypes[strings.ToLower(mr.tag)]; ok {
+				f = accepted
+				break
+			}
+			if mr.tag == "*/*" {
+				break
+			}
+		}
+	}
+	return f, &canonical
+}
+
+// variantURL returns the URL of the f representation of the canonical
+// page at u, with its path escaped.
+func variantURL(u *url.URL, f format) string {
+	p := u.Path
+	switch f {
+	case formatAMP:
+		p = "/amp" + p
+	case formatMarkdown, formatJSON, formatText:
+		if p == "/" {
+			p = "/index"
+		}
+		p += map[format]string{formatMarkdown: ".md", formatJSON: ".json", formatText: ".txt"}[f]
+	}
+	p = escapePath(p)
+	if u.RawQuery != "" {
+		p += "?" + u.RawQuery
+	}
+	return p
+}
+
+// renderAlternates links the other representations of the page at u from
+// the head of its f representation. AMP pages point back to the
+// canonical page instead of to an AMP version.
+func renderAlternates(u *url.URL, f format) string {
+	var b strings.Builder
+	for _, alt := range []struct {
+		f   format
+		typ string
+	}{{formatMarkdown, "text/markdown"}, {formatJSON, "application/json"}, {formatText, "text/plain"}} {
+		b.WriteString(fmt.Sprintf("\n    <link rel=\"alternate\" type=\"%s\" href=\"%s\">",
+			alt.typ, html.EscapeString(variantURL(u, alt.f))))
+	}
+	if f == formatAMP {
+		b.WriteString(fmt.Sprintf("\n    <link rel=\"canonical\" href=\"%s\">",
+			html.EscapeString(variantURL(u, formatHTML))))
+	} else {
+		b.WriteString(fmt.Sprintf("\n    <link rel=\"amphtml\" href=\"%s\">",
+			html.EscapeString(variantURL(u, formatAMP))))
+	}
+	return b.String()
+}
+
+// pagingVariant returns the f representation of a page link, or "" for
+// no link.
+func pagingVariant(link string, f format) string {
+	if link == "" {
+		return ""
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	return variantURL(u, f)
+}
+
+// jsonHead is the part of the JSON representation written before the
+// streamed body.
+type jsonHead struct {
+	Context       string      `json:"@context"`
+	Type          string      `json:"@type"`
+	Headline      string      `json:"headline"`
+	Description   string      `json:"description"`
+	Keywords      string      `json:"keywords,omitempty"`
+	Author        *jsonPerson `json:"author,omitempty"`
+	DatePublished string      `json:"datePublished,omitempty"`
+	URL           string      `json:"url"`
+	InLanguage    string      `json:"inLanguage"`
+}
+
+type jsonPerson struct {
+	Type string `json:"@type"`
+	Name string `json:"name"`
+}
+
+// jsonTail is the part of the JSON representation written after the
+// streamed body.
+type jsonTail struct {
+	Sections []jsonSection `json:"hasPart,omitempty"`
+	Related  []jsonLink    `json:"relatedLink,omitempty"`
+	Previous string        `json:"previous,omitempty"`
+	Next     string        `json:"next,omitempty"`
+}
+
+type jsonSection struct {
+	Headline string     `json:"headline"`
+	Text     string     `json:"text"`
+	Items    []string   `json:"items,omitempty"`
+	Table    *jsonTable `json:"table,omitempty"`
+	Code     string     `json:"code,omitempty"`
+	Language string     `json:"programmingLanguage,omitempty"`
+}
+
+type jsonTable struct {
+	Caption string     `json:"caption"`
+	Header  []string   `json:"header"`
+	Rows    [][]string `json:"rows"`
+}
+
+type jsonLink struct {
+	URL  string `json:"url"`
+	Text string `json:"text"`
+}
+
+// alternate holds what writeAlternate needs to render a page in a format
+// other than HTML.
+type alternate struct {
+	format   format
+	meta     PageMeta
+	lang     string
+	article  bool
+	sections []Section
+	related  []Link
+}
+
+// writeHeader writes what comes before the streamed body and returns the
+// sink the body is streamed into.
+func (a alternate) writeHeader(w io.Writer) tokenSink {
+	byline := ""
+	if a.article {
+		byline = fmt.Sprintf("By %s | Published %s", a.meta.Author, a.meta.DateStr)
+	}
+
+	switch a.format {
+	case formatMarkdown:
+		_, _ = fmt.Fprintf(w, "# %s\n\n", markdownEscaper.Replace(a.meta.Title))
+		if byline != "" {
+			_, _ = fmt.Fprintf(w, "*%s*\n\n", markdownEscaper.Replace(byline))
+		}
+		return newTextWriter(w, true)
+	case formatJSON:
+		head := jsonHead{
+			Context:     "https://schema.org",
+			Type:        a.meta.Type,
+			Headline:    a.meta.Title,
+			Description: a.meta.Description,
+			Keywords:    a.meta.Keywords,
+			URL:         a.meta.Path,
+			InLanguage:  a.lang,
+		}
+		key := "text"
+		if a.article {
+			head.Author = &jsonPerson{Type: "Person", Name: a.meta.Author}
+			head.DatePublished = a.meta.DateStr
+			key = "articleBody"
+		}
+		encoded, _ := json.Marshal(head)
+		_, _ = w.Write(encoded[:len(encoded)-1])
+		_, _ = fmt.Fprintf(w, `,"%s":"`, key)
+		return newTextWriter(jsonStringWriter{w: w}, false)
+	default:
+		_, _ = fmt.Fprintf(w, "%s\n\n", a.meta.Title)
+		if byline != "" {
+			_, _ = fmt.Fprintf(w, "%s\n\n", byline)
+		}
+		return newTextWriter(w, false)
+	}
+}
+
+// writeFooter writes the sections, related links and pagination that
+// follow the streamed body.
+func (a alternate) writeFooter(w io.Writer) {
+	prev := pagingVariant(a.meta.Prev, a.format)
+	next := pagingVariant(a.meta.Next, a.format)
+
+	if a.format == formatJSON {
+		tail := jsonTail{Previous: prev, Next: next}
+		for _, s := range a.sections {
+			section := jsonSection{Headline: s.Heading, Text: s.Content, Items: s.Items,
+				Code: s.Code, Language: s.CodeLang}
+			if s.Table != nil {
+				section.Table = &jsonTable{Caption: s.Table.Caption, Header: s.Table.Header, Rows: s.Table.Rows}
+			}
+			tail.Sections = append(tail.Sections, section)
+		}
+		for _, l := range a.related {
+			tail.Related = append(tail.Related, jsonLink(l))
+		}
+		encoded, _ := json.Marshal(tail)
+		_, _ = io.WriteString(w, `"`)
+		if len(encoded) > 2 {
+			_, _ = io.WriteString(w, ",")
+		}
+		_, _ = w.Write(encoded[1:])
+		_, _ = io.WriteString(w, "\n")
+		return
+	}
+
+	md := a.format == formatMarkdown
+	esc := func(s string) string {
+		if md {
+			return markdownEscaper.Replace(s)
+		}
+		return s
+	}
+	link := func(text, href string) string {
+		if md {
+			return fmt.Sprintf("[%s](%s)", esc(text), href)
+		}
+		return fmt.Sprintf("%s <%s>", text, href)
+	}
+
+	var b strings.Builder
+	for _, s := range a.sections {
+		b.WriteString(fmt.Sprintf("\n%s%s\n\n%s\n", mdMarker(md, "## "), esc(s.Heading), esc(s.Content)))
+		for _, item := range s.Items {
+			b.WriteString("\n- " + esc(item))
+		}
+		if len(s.Items) > 0 {
+			b.WriteString("\n")
+		}
+		if s.Table != nil {
+			b.WriteString("\n" + renderTextTable(*s.Table, md))
+		}
+		if s.Code != "" {
+			if md {
+				b.WriteString(fmt.Sprintf("\n```%s\n%s\n```\n", s.CodeLang, strings.TrimRight(s.Code, "\n")))
+			} else {
+				b.WriteString("\n" + s.Code + "\n")
+			}
+		}
+	}
+	if len(a.related) > 0 {
+		b.WriteString(fmt.Sprintf("\n%sRelated\n\n", mdMarker(md, "## ")))
+		for _, l := range a.related {
+			b.WriteString("- " + link(l.Text, l.URL) + "\n")
+		}
+	}
+	if prev != "" || next != "" {
+		b.WriteString("\n")
+		if prev != "" {
+			b.WriteString(link("Previous", prev) + "\n")
+		}
+		if next != "" {
+			b.WriteString(link("Next", next) + "\n")
+		}
+	}
+	_, _ = io.WriteString(w, b.String())
+}
+
+// renderTextTable renders t as a Markdown table, or as tab-separated
+// lines in plain text.
+func renderTextTable(t Table, md bool) string {
+	var b strings.Builder
+	sep := "\t"
+	if md {
+		b.WriteString("**" + markdownEscaper.Replace(t.Caption) + "**\n\n")
+		row := func(cells []string) {
+			b.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+		}
+		row(t.Header)
+		b.WriteString("|" + strings.Repeat(" --- |", len(t.Header)) + "\n")
+		for _, r := range t.Rows {
+			row(r)
+		}
+		return b.String()
+	}
+	b.WriteString(t.Caption + "\n")
+	b.WriteString(strings.Join(t.Header, sep) + "\n")
+	for _, r := range t.Rows {
+		b.WriteString(strings.Join(r, sep) + "\n")
+	}
+	return b.String()
+}
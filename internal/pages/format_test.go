@@ -0,0 +1,95 @@
+package pages
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	tests := []struct {
+		target    string
+		accept    string
+		want      format
+		wantPath  string
+		wantQuery string
+	}{
+		{"/news/strike", "", formatHTML, "/news/strike", ""},
+		{"/", "", formatHTML, "/", ""},
+
+		{"/amp", "", formatAMP, "/", ""},
+		{"/amp/", "", formatAMP, "/", ""},
+		{"/amp/news/strike", "", formatAMP, "/news/strike", ""},
+		{"/ampere", "", formatHTML, "/ampere", ""},
+
+		{"/index.md", "", formatMarkdown, "/", ""},
+		{"/news/strike.md", "", formatMarkdown, "/news/strike", ""},
+		{"/news/strike.json", "", formatJSON, "/news/strike", ""},
+		{"/news/strike.txt", "", formatText, "/news/strike", ""},
+		{"/amp/index.md", "", formatAMP, "/", ""},
+
+		{"/news/strike?format=md&page=2", "", formatMarkdown, "/news/strike", "page=2"},
+		{"/news/strike?format=JSON", "", formatJSON, "/news/strike", ""},
+		{"/news/strike.json?format=text", "", formatText, "/news/strike", ""},
+		{"/amp/news/strike?format=html", "", formatHTML, "/news/strike", ""},
+		{"/news/strike?format=bogus", "", formatHTML, "/news/strike", ""},
+
+		{"/news/strike", "text/markdown", formatMarkdown, "/news/strike", ""},
+		{"/news/strike", "text/html, application/json", formatHTML, "/news/strike", ""},
+		{"/news/strike", "text/plain;q=0.5, application/json", formatJSON, "/news/strike", ""},
+		{"/news/strike", "*/*, text/plain", formatHTML, "/news/strike", ""},
+		{"/news/strike.txt", "application/json", formatText, "/news/strike", ""},
+
+		{"/tag/a%20b%3Fc.md", "", formatMarkdown, "/tag/a b?c", ""},
+		{"/amp/tag/%22%3E%3Cscript%3E", "", formatAMP, `/tag/"><script>`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tt.target, nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+			f, canonical := negotiateFormat(r)
+			if f != tt.want || canonical.Path != tt.wantPath || canonical.RawQuery != tt.wantQuery {
+				t.Errorf("negotiateFormat(%s, Accept %q) = %d, %q?%s, want %d, %q?%s",
+					tt.target, tt.accept, f, canonical.Path, canonical.RawQuery,
+					tt.want, tt.wantPath, tt.wantQuery)
+			}
+		})
+	}
+}
+
+func TestVariantURL(t *testing.T) {
+	tests := []struct {
+		path  string
+		query string
+		f     format
+		want  string
+	}{
+		{"/", "", formatHTML, "/"},
+		{"/", "", formatAMP, "/amp/"},
+		{"/", "", formatMarkdown, "/index.md"},
+		{"/news/strike", "", formatJSON, "/news/strike.json"},
+		{"/news/strike", "", formatText, "/news/strike.txt"},
+		{"/news/strike", "page=2", formatMarkdown, "/news/strike.md?page=2"},
+		{"/news/strike", "page=2", formatAMP, "/amp/news/strike?page=2"},
+		{"/tag/a b?c", "", formatText, "/tag/a%20b%3Fc.txt"},
+		{"/tag/a b?c", "", formatAMP, "/amp/tag/a%20b%3Fc"},
+		{`/tag/"><script>`, "", formatHTML, "/tag/%22%3E%3Cscript%3E"},
+	}
+	for _, tt := range tests {
+		u := &url.URL{Path: tt.path, RawQuery: tt.query}
+		got := variantURL(u, tt.f)
+		if got != tt.want {
+			t.Errorf("variantURL(%q, %d) = %q, want %q", u, tt.f, got, tt.want)
+			continue
+		}
+
+		// Every variant negotiates back to its format and the same page.
+		f, canonical := negotiateFormat(httptest.NewRequest("GET", got, nil))
+		if f != tt.f || canonical.Path != tt.path || canonical.RawQuery != tt.query {
+			t.Errorf("negotiateFormat(%s) = %d, %q?%s, want %d, %q?%s",
+				got, f, canonical.Path, canonical.RawQuery, tt.f, tt.path, tt.query)
+		}
+	}
+}
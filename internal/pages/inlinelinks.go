@@ -0,0 +1,91 @@
+package pages
+
+import (
+	"fmt"
+	"html"
+	"iter"
+	"math/rand/v2"
+	"net/url"
+	"strings"
+	"unicode"
+
+	"Erebus/internal/bable"
+)
+
+// nonNounSuffixes mostly end adverbs, adjectives and verb forms.
+var nonNounSuffixes = []string{"ly", "ed", "ing", "ous", "ful", "ive", "able", "ible", "al"}
+
+// isNounLike guesses whether word, stripped of punctuation, is a noun:
+// a longer word that isn't a stop word and doesn't end like an adverb,
+// adjective or verb form.
+func isNounLike(word string) bool {
+	if len(word) < 5 || !isLowerWord(word) || bable.IsStopWord(word) {
+		return false
+	}
+	for _, suffix := range nonNounSuffixes {
+		if strings.HasSuffix(word, suffix) {
+			return false
+		}
+	}
+	return true
+}
+
+// linkNouns turns noun-like words of the body into links into the maze,
+// picking each with probability density. Links go either to the tag page
+// of the word itself or to a generated article. Words inside elements
+// that are already links are left alone, and so is the session's canary,
+// which must not end up in a URL as well.
+//
+// The links draw from their own fork of the page, the same number of
+// times for every token whatever its word. Otherwise the canary would
+// shift every later draw and the body would differ between sessions
+// from the first canary on.
+func (p *Page) linkNouns(tokens iter.Seq[bodyToken], density float64, canary string) iter.Seq[bodyToken] {
+	if density <= 0 {
+		return tokens
+	}
+	links := p.fork()
+	return func(yield func(bodyToken) bool) {
+		var open []string
+		for token := range tokens {
+			roll, seed := links.rng.Float64(), links.rng.Uint64()
+			switch {
+			case token.closing && len(open) > 0:
+				open = open[:len(open)-1]
+			case token.tag != "":
+				open = append(open, token.tag)
+			}
+			if token.word == "" || (len(open) > 0 && open[len(open)-1] == "a") {
+				if !yield(token) {
+					return
+				}
+				continue
+			}
+
+			bare := strings.TrimRightFunc(token.word, unicode.IsPunct)
+			if !isNounLike(bare) || bare == canary || roll >= density {
+				if !yield(token) {
+					return
+				}
+				continue
+			}
+
+			// The target gets an RNG of its own from the token's seed,
+			// however many draws generating it takes.
+			target := *links
+			target.rng = rand.New(rand.NewPCG(seed, seed)) //nolint:gosec // not used for security
+			href := "/tag/" + url.PathEscape(bare)
+			if target.rng.IntN(2) == 0 {
+				href = target.generateOneLink().URL
+			}
+			trailing := token.word[len(bare):]
+			if !yield(openToken("a", fmt.Sprintf(`href="%s"`, html.EscapeString(href)))) ||
+				!yield(bodyToken{word: bare, glue: token.glue}) || !yield(closeToken()) {
+				return
+			}
+			if trailing != "" && !yield(bodyToken{word: trailing, glue: true}) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package pages
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"Erebus/internal/bable"
+)
+
+// languageRange is one entry of an Accept-Language header.
+type languageRange struct {
+	tag string
+	q   float64
+}
+
+// negotiateLanguage picks the best language in available for an
+// Accept-Language header. Ranges are tried by descending quality; a range
+// matches a language exactly or by its primary subtag, so "de-AT" selects
+// "de". Without a match the default language is used.
+func negotiateLanguage(header string, available []string) string {
+	fallback := bable.DefaultLanguage
+	if len(available) > 0 && !containsFold(available, fallback) {
+		fallback = available[0]
+	}
+
+	for _, lr := range parseAcceptLanguage(header) {
+		if lr.tag == "*" {
+			return fallback
+		}
+		for _, lang := range available {
+			if strings.EqualFold(lang, lr.tag) {
+				return lang
+			}
+		}
+		primary, _, _ := strings.Cut(lr.tag, "-")
+		for _, lang := range available {
+			if strings.EqualFold(lang, primary) {
+				return lang
+			}
+		}
+	}
+	return fallback
+}
+
+// parseAcceptLanguage splits header into ranges sorted by descending
+// quality, dropping ranges with q=0 or a malformed q value.
+func parseAcceptLanguage(header string) []languageRange {
+	var ranges []languageRange
+	for part := range strings.SplitSeq(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q <= 0 {
+			continue
+		}
+		ranges = append(ranges, languageRange{tag: tag, q: q})
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].q > ranges[j].q
+	})
+	return ranges
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
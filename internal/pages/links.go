@@ -2,12 +2,9 @@ package pages
 
 import (
 	"fmt"
-	"math/rand/v2"
 	"strings"
 	"time"
 	"unicode"
-
-	"Erebus/internal/bable"
 )
 
 // Link represents a hyperlink on a generated page.
@@ -16,15 +13,6 @@ type Link struct {
 	Text string
 }
 
-var stopWords = map[string]bool{
-	"the": true, "a": true, "an": true, "and": true, "or": true,
-	"of": true, "in": true, "to": true, "for": true, "is": true,
-	"it": true, "by": true, "on": true, "at": true, "as": true,
-	"its": true, "was": true, "are": true, "be": true, "has": true,
-	"had": true, "have": true, "with": true, "from": true, "this": true,
-	"that": true, "which": true, "but": true, "not": true, "all": true,
-}
-
 var categories = []string{
 	"articles", "blog", "news", "analysis",
 	"research", "reports", "archive", "documents",
@@ -50,27 +38,9 @@ var lastNames = []string{
 	"jackson", "martin", "lee", "thompson", "white",
 }
 
-// GenerateSlug produces a URL-friendly slug from Markov-generated words.
-func GenerateSlug(wordCount int) string {
-	raw := bable.Bable(1, 1)
-	words := strings.Fields(strings.ToLower(raw))
-
-	var kept []string
-	for _, w := range words {
-		cleaned := stripNonAlpha(w)
-		if cleaned == "" || stopWords[cleaned] {
-			continue
-		}
-		kept = append(kept, cleaned)
-		if len(kept) >= wordCount {
-			break
-		}
-	}
-
-	if len(kept) == 0 {
-		return "page"
-	}
-	return strings.Join(kept, "-")
+// GenerateSlug produces a URL-friendly slug from generated words.
+func (p *Page) GenerateSlug(wordCount int) string {
+	return p.gen.Slug(p.rng, wordCount)
 }
 
 // titleCase capitalises the first letter of each word.
@@ -97,45 +67,59 @@ func stripNonAlpha(s string) string {
 }
 
 // GenerateAuthorName produces a realistic author name.
-func GenerateAuthorName() string {
-	first := firstNames[rand.IntN(len(firstNames))] //nolint:gosec
-	last := lastNames[rand.IntN(len(lastNames))]   //nolint:gosec
+func (p *Page) GenerateAuthorName() string {
+	first := firstNames[p.rng.IntN(len(firstNames))]
+	last := lastNames[p.rng.IntN(len(lastNames))]
 	return titleCase(first) + " " + titleCase(last)
 }
 
-// GenerateRecentDate returns a date within the past 60 days.
-func GenerateRecentDate() time.Time {
-	daysAgo := rand.IntN(60) //nolint:gosec
-	return time.Now().AddDate(0, 0, -daysAgo)
+// dateYears is how many years back generated dates reach.
+const dateYears = 3
+
+// dateEpoch is the start of the window generated dates fall into, which
+// runs for dateYears up to the start of the current month. Anchoring it
+// to the month rather than today keeps a page's dates the same on every
+// fetch within a month, while they never fall far behind the present.
+func dateEpoch() time.Time {
+	now := time.Now().UTC()
+	return time.Date(now.Year()-dateYears, now.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// GenerateDate returns a publication date within the dateYears before the
+// current month.
+func (p *Page) GenerateDate() time.Time {
+	epoch := dateEpoch()
+	days := int(epoch.AddDate(dateYears, 0, 0).Sub(epoch) / (24 * time.Hour))
+	return epoch.AddDate(0, 0, p.rng.IntN(days))
 }
 
 // RandomCategory picks a random category slug.
-func RandomCategory() string {
-	return categories[rand.IntN(len(categories))] //nolint:gosec
+func (p *Page) RandomCategory() string {
+	return categories[p.rng.IntN(len(categories))]
 }
 
 // RandomMonth picks a random month name.
-func RandomMonth() string {
-	return months[rand.IntN(len(months))] //nolint:gosec
+func (p *Page) RandomMonth() string {
+	return months[p.rng.IntN(len(months))]
 }
 
 // GenerateLinks produces a set of links with realistic URL patterns.
-func GenerateLinks(count int) []Link {
+func (p *Page) GenerateLinks(count int) []Link {
 	links := make([]Link, 0, count)
 	for range count {
-		links = append(links, generateOneLink())
+		links = append(links, p.generateOneLink())
 	}
 	return links
 }
 
-func generateOneLink() Link {
-	slug := GenerateSlug(3 + rand.IntN(2))     //nolint:gosec
-	text := bable.Bable(1, 1)
-	year := 2023 + rand.IntN(3)               //nolint:gosec
+func (p *Page) generateOneLink() Link {
+	slug := p.GenerateSlug(3 + p.rng.IntN(2))
+	text := p.gen.Title(p.rng, 8)
+	year := dateEpoch().Year() + p.rng.IntN(dateYears)
 
 	patterns := []func() Link{
 		func() Link {
-			cat := RandomCategory()
+			cat := p.RandomCategory()
 			return Link{
 				URL:  fmt.Sprintf("/%s/%s", cat, slug),
 				Text: text,
@@ -143,7 +127,7 @@ func generateOneLink() Link {
 		},
 		func() Link {
 			return Link{
-				URL:  fmt.Sprintf("/articles/%d/%02d/%s", year, 1+rand.IntN(12), slug), //nolint:gosec
+				URL:  fmt.Sprintf("/articles/%d/%02d/%s", year, 1+p.rng.IntN(12), slug),
 				Text: text,
 			}
 		},
@@ -154,28 +138,27 @@ func generateOneLink() Link {
 			}
 		},
 		func() Link {
-			word := GenerateSlug(1)
+			word := p.GenerateSlug(1)
 			return Link{
 				URL:  fmt.Sprintf("/tag/%s", word),
 				Text: text,
 			}
 		},
 		func() Link {
-			name := strings.ToLower(strings.ReplaceAll(GenerateAuthorName(), " ", "-"))
 			return Link{
-				URL:  fmt.Sprintf("/author/%s", name),
+				URL:  fmt.Sprintf("/author/%s", authorSlug(p.GenerateAuthorName())),
 				Text: text,
 			}
 		},
 		func() Link {
 			return Link{
-				URL:  fmt.Sprintf("/archive/%d/%s", year, RandomMonth()),
+				URL:  fmt.Sprintf("/archive/%d/%s", year, p.RandomMonth()),
 				Text: text,
 			}
 		},
 	}
 
-	return patterns[rand.IntN(len(patterns))]() //nolint:gosec
+	return patterns[p.rng.IntN(len(patterns))]()
 }
 
 // GenerateNavLinks returns fixed-looking navigation category links.
@@ -189,34 +172,3 @@ func GenerateNavLinks() []Link {
 	}
 	return nav
 }
-
-// GeneratePaginationLinks creates prev/next and page number links.
-func GeneratePaginationLinks(basePath string) []Link {
-	totalPages := 5 + rand.IntN(20)           //nolint:gosec
-	currentPage := 1 + rand.IntN(totalPages)  //nolint:gosec
-
-	var links []Link
-	if currentPage > 1 {
-		links = append(links, Link{
-			URL:  fmt.Sprintf("%s?page=%d", basePath, currentPage-1),
-			Text: "Previous",
-		})
-	}
-
-	start := max(1, currentPage-2)
-	end := min(totalPages, currentPage+2)
-	for p := start; p <= end; p++ {
-		links = append(links, Link{
-			URL:  fmt.Sprintf("%s?page=%d", basePath, p),
-			Text: fmt.Sprintf("%d", p),
-		})
-	}
-
-	if currentPage < totalPages {
-		links = append(links, Link{
-			URL:  fmt.Sprintf("%s?page=%d", basePath, currentPage+1),
-			Text: "Next",
-		})
-	}
-	return links
-}
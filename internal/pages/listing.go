@@ -0,0 +1,268 @@
+package pages
+
+import (
+	"fmt"
+	"html"
+	"iter"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// title returns the heading of a listing page.
+func (rt route) title() string {
+	switch rt.kind {
+	case authorPage:
+		return authorName(rt.name)
+	case tagPage:
+		return "Tag: " + titleCase(strings.ReplaceAll(rt.name, "-", " "))
+	case archivePage:
+		if rt.month == 0 {
+			return fmt.Sprintf("Archive: %d", rt.year)
+		}
+		return fmt.Sprintf("Archive: %s %d", rt.month, rt.year)
+	case categoryPage:
+		return titleCase(rt.name)
+	}
+	return ""
+}
+
+// schemaType returns the schema.org type describing a page of this kind.
+func (rt route) schemaType() string {
+	switch rt.kind {
+	case articlePage:
+		return "Article"
+	case authorPage:
+		return "ProfilePage"
+	}
+	return "CollectionPage"
+}
+
+// teasersPerPage is how many posts one page of a listing shows.
+const teasersPerPage = 12
+
+// listing streams an index of posts for page rt.page of a listing route
+// with total pages: an introduction fitting the page type, a calendar or
+// month index for archives, and then teasersPerPage teasers whose
+// excerpts are taken from sentences.
+func (p *Page) listing(rt route, total int, sentences iter.Seq[string]) iter.Seq[bodyToken] {
+	return func(yield func(bodyToken) bool) {
+		next, stop := iter.Pull(sentences)
+		defer stop()
+
+		emit := func(tokens ...bodyToken) bool {
+			for _, token := range tokens {
+				if !yield(token) {
+					return false
+				}
+			}
+			return true
+		}
+		words := func(text string) bool {
+			for _, word := range strings.Fields(text) {
+				if !yield(wordToken(word)) {
+					return false
+				}
+			}
+			return true
+		}
+		link := func(href, text string) bool {
+			return emit(openToken("a", fmt.Sprintf(`href="%s"`, html.EscapeString(href)))) &&
+				words(text) && emit(closeToken())
+		}
+
+		lead, ok := next()
+		if !ok || !emit(openToken("p", `class="intro"`)) || !words(p.introduction(rt)) ||
+			!words(lead) || !emit(closeToken()) {
+			return
+		}
+		if rt.kind == archivePage {
+			if !p.archiveIndex(rt, emit, words, link) {
+				return
+			}
+		}
+
+		for _, date := range p.listingDates(rt, total, teasersPerPage) {
+			post := p.teaser(rt, date)
+			if !emit(openToken("article", `class="teaser"`), openToken("h2", "")) ||
+				!link(post.URL, post.Text) || !emit(closeToken()) {
+				return
+			}
+			if !emit(openToken("p", `class="teaser-meta"`)) || !words(post.date.Format("January 2, 2006")) ||
+				!words("·") || !link("/author/"+authorSlug(post.author), post.author) || !emit(closeToken()) {
+				return
+			}
+			if !emit(openToken("p", "")) {
+				return
+			}
+			for range 1 + p.rng.IntN(2) {
+				sentence, ok := next()
+				if !ok {
+					return
+				}
+				if !words(sentence) {
+					return
+				}
+			}
+			if !link(post.URL, "Continue reading") || !emit(closeToken(), closeToken()) {
+				return
+			}
+		}
+	}
+}
+
+// introduction opens a listing page with a line that fits its type.
+func (p *Page) introduction(rt route) string {
+	switch rt.kind {
+	case authorPage:
+		f := p.facts()
+		org := f.orgs[p.rng.IntN(len(f.orgs))]
+		role := roles[p.rng.IntN(len(roles))]
+		return fmt.Sprintf("%s is %s %s at the %s, based in %s.",
+			authorName(rt.name), indefinite(role), role, org.name, f.places[org.place].name)
+	case tagPage:
+		return fmt.Sprintf("Everything we have published about %s.", strings.ReplaceAll(rt.name, "-", " "))
+	case archivePage:
+		return fmt.Sprintf("Posts from %s, newest first.", strings.TrimPrefix(rt.title(), "Archive: "))
+	}
+	return fmt.Sprintf("The latest from our %s section.", rt.name)
+}
+
+// indefinite returns the indefinite article for word.
+func indefinite(word string) string {
+	if strings.ContainsRune("aeiou", rune(word[0])) {
+		return "an"
+	}
+	return "a"
+}
+
+// post is a teaser on a listing page.
+type post struct {
+	Link
+	date   time.Time
+	author string
+}
+
+// teaser invents a post for the listing, dated date. Posts on author
+// pages are by that author, and posts on tag pages carry the tag in their
+// slug so the articles they lead to are about it.
+func (p *Page) teaser(rt route, date time.Time) post {
+	slug := p.GenerateSlug(3 + p.rng.IntN(2))
+	cat := p.RandomCategory()
+	author := p.GenerateAuthorName()
+	switch rt.kind {
+	case authorPage:
+		author = authorName(rt.name)
+	case tagPage:
+		slug = url.PathEscape(rt.name) + "-" + slug
+	case categoryPage:
+		cat = rt.name
+	}
+	href := fmt.Sprintf("/%s/%s", cat, slug)
+	if rt.kind == archivePage {
+		href = fmt.Sprintf("/articles/%d/%02d/%s", date.Year(), date.Month(), slug)
+	}
+	return post{
+		Link:   Link{URL: href, Text: p.gen.Title(p.rng, 8)},
+		date:   date,
+		author: author,
+	}
+}
+
+// listingDates returns n post dates for page rt.page of a listing with
+// total pages, newest first. The listing's period is shared out evenly
+// between its pages, so each page picks up where the one before it
+// ended and walking the pages goes back in time without overlaps.
+func (p *Page) listingDates(rt route, total, n int) []time.Time {
+	newest, oldest := listingPeriod(rt)
+	span := max(newest.Sub(oldest)/time.Duration(max(total, 1)), time.Second)
+	start := newest.Add(-time.Duration(max(rt.page, 1)-1) * span)
+	dates := make([]time.Time, n)
+	for i := range dates {
+		dates[i] = start.Add(-time.Duration(1 + p.rng.Int64N(int64(span))))
+	}
+	slices.SortFunc(dates, func(a, b time.Time) int { return b.Compare(a) })
+	return dates
+}
+
+// listingPeriod returns the time a listing's posts span: the period of
+// an archive, or the whole window of generated dates for anything else.
+func listingPeriod(rt route) (newest, oldest time.Time) {
+	if rt.kind != archivePage {
+		epoch := dateEpoch()
+		return epoch.AddDate(dateYears, 0, 0), epoch
+	}
+	oldest = time.Date(rt.year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	newest = oldest.AddDate(1, 0, 0)
+	if rt.month != 0 {
+		oldest = time.Date(rt.year, rt.month, 1, 0, 0, 0, 0, time.UTC)
+		newest = oldest.AddDate(0, 1, 0)
+	}
+	return newest, oldest
+}
+
+// archiveIndex writes a calendar of the month for month archives, linking
+// the days with posts, or the months of the year with their post counts.
+func (p *Page) archiveIndex(rt route, emit func(...bodyToken) bool,
+	words func(string) bool, link func(href, text string) bool) bool {
+	if rt.month == 0 {
+		if !emit(openToken("ul", `class="archive-months"`)) {
+			return false
+		}
+		for month := time.December; month >= time.January; month-- {
+			if !emit(openToken("li", "")) ||
+				!link(fmt.Sprintf("/archive/%d/%s", rt.year, months[month-1]), month.String()) ||
+				!words(fmt.Sprintf("(%d)", 4+p.rng.IntN(40))) || !emit(closeToken()) {
+				return false
+			}
+		}
+		return emit(closeToken())
+	}
+
+	first := time.Date(rt.year, rt.month, 1, 0, 0, 0, 0, time.UTC)
+	days := first.AddDate(0, 1, -1).Day()
+	if !emit(openToken("table", `class="calendar"`), openToken("caption", "")) ||
+		!words(fmt.Sprintf("%s %d", rt.month, rt.year)) || !emit(closeToken(), openToken("tr", "")) {
+		return false
+	}
+	for _, day := range []string{"Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"} {
+		if !emit(openToken("th", "")) || !words(day) || !emit(closeToken()) {
+			return false
+		}
+	}
+	if !emit(closeToken()) {
+		return false
+	}
+
+	// Weeks start on Monday; blank cells pad the first and last week.
+	offset := (int(first.Weekday()) + 6) % 7
+	for cell := 0; cell < offset+days || cell%7 != 0; cell++ {
+		if cell%7 == 0 && !emit(openToken("tr", "")) {
+			return false
+		}
+		if !emit(openToken("td", "")) {
+			return false
+		}
+		if day := cell - offset + 1; day >= 1 && day <= days {
+			var ok bool
+			if p.rng.IntN(3) > 0 {
+				href := fmt.Sprintf("/articles/%d/%02d/%s", rt.year, rt.month, p.GenerateSlug(3))
+				ok = link(href, strconv.Itoa(day))
+			} else {
+				ok = words(strconv.Itoa(day))
+			}
+			if !ok {
+				return false
+			}
+		}
+		if !emit(closeToken()) {
+			return false
+		}
+		if cell%7 == 6 && !emit(closeToken()) {
+			return false
+		}
+	}
+	return emit(closeToken())
+}
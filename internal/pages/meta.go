@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"html"
 	"strings"
+
+	"Erebus/internal/bable"
 )
 
 // PageMeta holds SEO metadata for a generated page.
@@ -14,15 +16,32 @@ type PageMeta struct {
 	Author      string
 	DateStr     string
 	Path        string
+	// Type is the schema.org type of the page, Article when empty.
+	Type string
+	// Prev and Next are the neighbouring pages of a paginated sequence.
+	Prev string
+	Next string
+}
+
+// truncateText shortens s to at most limit characters, ellipsis
+// included. It cuts at the last space before the limit, or between two
+// characters for scripts written without spaces, never inside one.
+func truncateText(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	cut := string(runes[:limit-3])
+	if i := strings.LastIndexByte(cut, ' '); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " ,;:") + "..."
 }
 
 // GenerateMeta builds page metadata from generated content.
-func GenerateMeta(title, content, path string) PageMeta {
+func (p *Page) GenerateMeta(title, content, path string) PageMeta {
 	// Use first ~160 chars of content as description
-	desc := content
-	if len(desc) > 160 {
-		desc = desc[:157] + "..."
-	}
+	desc := truncateText(content, 160)
 
 	// Pull some keywords from the content
 	words := strings.Fields(strings.ToLower(content))
@@ -30,7 +49,7 @@ func GenerateMeta(title, content, path string) PageMeta {
 	seen := make(map[string]bool)
 	for _, w := range words {
 		cleaned := stripNonAlpha(w)
-		if cleaned == "" || stopWords[cleaned] ||
+		if cleaned == "" || bable.IsStopWord(cleaned) ||
 			len(cleaned) < 4 || seen[cleaned] {
 			continue
 		}
@@ -45,14 +64,18 @@ func GenerateMeta(title, content, path string) PageMeta {
 		Title:       title,
 		Description: desc,
 		Keywords:    strings.Join(kw, ", "),
-		Author:      GenerateAuthorName(),
-		DateStr:     GenerateRecentDate().Format("2006-01-02"),
+		Author:      p.GenerateAuthorName(),
+		DateStr:     p.GenerateDate().Format("2006-01-02"),
 		Path:        path,
 	}
 }
 
 // RenderHead returns the HTML <meta>, Open Graph, and JSON-LD markup.
+// Listing pages describe themselves by name rather than as an article.
 func (m PageMeta) RenderHead() string {
+	if m.Type != "" && m.Type != "Article" {
+		return m.renderListingHead() + m.renderPagingHead()
+	}
 	escaped := struct {
 		Title string
 		Desc  string
@@ -101,5 +124,52 @@ func (m PageMeta) RenderHead() string {
 		escaped.Date,
 		escaped.Date,
 		escaped.Auth,
+	) + m.renderPagingHead()
+}
+
+// renderListingHead is RenderHead for author, tag, archive and category
+// pages.
+func (m PageMeta) renderListingHead() string {
+	ogType := "website"
+	if m.Type == "ProfilePage" {
+		ogType = "profile"
+	}
+	return fmt.Sprintf(`    <meta name="description" content="%s">
+    <meta name="keywords" content="%s">
+    <meta property="og:title" content="%s">
+    <meta property="og:description" content="%s">
+    <meta property="og:type" content="%s">
+    <meta property="og:url" content="%s">
+    <script type="application/ld+json">
+    {
+        "@context": "https://schema.org",
+        "@type": "%s",
+        "name": "%s",
+        "description": "%s",
+        "url": "%s"
+    }
+    </script>`,
+		html.EscapeString(m.Description),
+		html.EscapeString(m.Keywords),
+		html.EscapeString(m.Title),
+		html.EscapeString(m.Description),
+		ogType,
+		html.EscapeString(m.Path),
+		m.Type,
+		html.EscapeString(m.Title),
+		html.EscapeString(m.Description),
+		html.EscapeString(m.Path),
 	)
 }
+
+// renderPagingHead links the neighbouring pages of a sequence.
+func (m PageMeta) renderPagingHead() string {
+	var b strings.Builder
+	if m.Prev != "" {
+		b.WriteString(fmt.Sprintf("\n    <link rel=\"prev\" href=\"%s\">", html.EscapeString(m.Prev)))
+	}
+	if m.Next != "" {
+		b.WriteString(fmt.Sprintf("\n    <link rel=\"next\" href=\"%s\">", html.EscapeString(m.Next)))
+	}
+	return b.String()
+}
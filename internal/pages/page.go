@@ -0,0 +1,95 @@
+package pages
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+
+	"Erebus/internal/bable"
+	"Erebus/internal/erebusconfig"
+)
+
+// Page generates the content for a single URL.
+// All of its randomness comes from one RNG seeded with the site secret and
+// the request's path and query, so fetching the same URL twice renders the
+// same title, author, date, body and links.
+type Page struct {
+	rng     *rand.Rand
+	gen     bable.Generator
+	genName string
+	lang    string
+	degrade erebusconfig.Degrade
+	// data holds the facts the page cites, see Page.facts.
+	data *facts
+}
+
+// NewPage returns a Page for r, seeded from its URL and using the
+// generator configured for its host and path in the language negotiated
+// from its Accept-Language header. The text is steered toward the words
+// in the path, so a page looks like it is about what its URL says.
+func NewPage(r *http.Request) *Page {
+	lang := negotiateLanguage(r.Header.Get("Accept-Language"), bable.Languages())
+	policy := erebusconfig.Conf.PolicyFor(r.Host, r.URL.Path)
+	name, gen := generatorFor(policy.Generator, lang)
+	if seeds := topicSeeds(r.URL.Path); len(seeds) > 0 {
+		gen = gen.About(seeds)
+	}
+	return &Page{
+		rng:     seededRand(r.URL),
+		gen:     gen,
+		genName: name,
+		lang:    lang,
+		degrade: policy.Degrade,
+	}
+}
+
+// seededRand derives a deterministic RNG from the site secret and the URL.
+// The query is re-encoded so parameter order doesn't matter, and the
+// keepalive parameter the template's script appends is ignored. So is
+// ?page=, since every page of a sequence shares its title and meta.
+func seededRand(u *url.URL) *rand.Rand {
+	query := u.Query()
+	query.Del("keepalive")
+	query.Del("page")
+
+	h := sha256.New()
+	h.Write([]byte(erebusconfig.Conf.SiteSecret))
+	h.Write([]byte{0})
+	h.Write([]byte(u.Path))
+	h.Write([]byte{'?'})
+	h.Write([]byte(query.Encode()))
+	sum := h.Sum(nil)
+
+	return rand.New(rand.NewPCG( //nolint:gosec // not used for security
+		binary.LittleEndian.Uint64(sum[0:8]),
+		binary.LittleEndian.Uint64(sum[8:16]),
+	))
+}
+
+// fork returns a copy of the page with its own RNG seeded from the page
+// RNG, for output whose length varies between requests and so must not
+// consume the page RNG.
+func (p *Page) fork() *Page {
+	child := *p
+	child.rng = rand.New(rand.NewPCG(p.rng.Uint64(), p.rng.Uint64())) //nolint:gosec // not used for security
+	child.data = nil
+	return &child
+}
+
+// generatorFor resolves the text backend called name, falling back to the
+// default backend if it is unknown.
+func generatorFor(name, lang string) (string, bable.Generator) {
+	gen, err := bable.GeneratorFor(name, lang)
+	if err != nil {
+		log.Printf("falling back to default generator: %s", err.Error())
+		name = bable.DefaultGenerator
+		gen, _ = bable.GeneratorFor(name, lang)
+	}
+	if name == "" {
+		name = bable.DefaultGenerator
+	}
+	return name, gen
+}
@@ -0,0 +1,30 @@
+package pages
+
+import (
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"Erebus/internal/bable"
+)
+
+// BenchmarkNewPage measures the per-page generation cost: a seeded page,
+// its sections and the three batches of links every page renders. The
+// chains are built once up front, as main does at startup.
+func BenchmarkNewPage(b *testing.B) {
+	// The corpora live in the repository root.
+	b.Chdir("../..")
+	bable.Warm()
+
+	b.ReportAllocs()
+	n := 0
+	for b.Loop() {
+		n++
+		r := httptest.NewRequest("GET", "/articles/"+strconv.Itoa(n), nil)
+		page := NewPage(r)
+		page.GenerateSections(3)
+		page.GenerateLinks(10)
+		page.GenerateLinks(6)
+		page.GenerateLinks(9)
+	}
+}
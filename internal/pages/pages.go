@@ -5,13 +5,12 @@ import (
 	"fmt"
 	"html"
 	"html/template"
+	"iter"
 	"log"
 	"math/rand/v2"
 	"net/http"
-	"strings"
 	"time"
 
-	"Erebus/internal/bable"
 	"Erebus/internal/erebusconfig"
 	"Erebus/internal/session"
 )
@@ -33,7 +32,51 @@ func generateHandler(rc *session.Client, w http.ResponseWriter, r *http.Request)
 		log.Printf("failed to store IP in cache: %s", err.Error())
 	}
 
-	generatedText := bable.Bable(50, 5)
+	// Feeds live under the pages they syndicate, so they are routed here
+	// rather than on the mux.
+	if ff, base, ok := parseFeed(r.URL.Path); ok {
+		serveFeed(w, r, ff, base)
+		return
+	}
+
+	// Every representation of a URL renders the same page, so the page is
+	// built from the canonical URL with the format markers stripped.
+	f, canonical := negotiateFormat(r)
+	cr := r.Clone(r.Context())
+	cr.URL = canonical
+	page := NewPage(cr)
+	if err := rc.SetGenerator(r, page.genName); err != nil {
+		log.Printf("failed to store generator in cache: %s", err.Error())
+	}
+	canary, err := rc.Canary(r)
+	if err != nil {
+		log.Printf("failed to issue canary: %s", err.Error())
+	}
+
+	// Every page of a sequence shares its base path and page count; pages
+	// past the end don't exist. The count follows the base path's own
+	// route, so the homepage pages through /?page=N rather than into the
+	// /articles listing, which is a sequence of another length.
+	basePath := canonical.Path
+	rt := parseRoute(basePath)
+	rt.page = pageParam(canonical)
+	totalPages := pageCount(basePath, rt.kind)
+	if rt.page > totalPages {
+		http.NotFound(w, r)
+		return
+	}
+
+	// The lead opens the body and feeds the meta description; the rest of
+	// the body is generated by a fork of the page so that however long it
+	// runs, the sections and links after it stay the same for this URL.
+	lead := page.gen.Sentences(page.rng, 5)
+	article := page.fork()
+	if rt.page > 1 {
+		// Every page of a sequence shares the title and meta drawn from
+		// the base path; the page number only picks the body.
+		seed := article.rng.Uint64()
+		article.rng = rand.New(rand.NewPCG(seed, uint64(rt.page))) //nolint:gosec // not used for security
+	}
 
 	flusher, ok := w.(http.Flusher)
 	if !ok {
@@ -42,7 +85,9 @@ func generateHandler(rc *session.Client, w http.ResponseWriter, r *http.Request)
 		return
 	}
 	// Set headers to prevent timeouts and caching
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Type", f.contentType())
+	w.Header().Set("Content-Language", page.lang)
+	w.Header().Set("Vary", "Accept, Accept-Language")
 	w.Header().Set("Cache-Control", "no-store, no-cache, must-revalidate, max-age=0")
 	w.Header().Set("Pragma", "no-cache")
 	w.Header().Set("Expires", "0")
@@ -50,16 +95,69 @@ func generateHandler(rc *session.Client, w http.ResponseWriter, r *http.Request)
 	w.Header().Set("Transfer-Encoding", "chunked")
 	w.Header().Set("Connection", "keep-alive")
 
-	// Build a multi-word title from the generated text
-	titleWords := strings.Fields(generatedText)
-	titleLen := 3 + rand.IntN(4) //nolint:gosec
-	if titleLen > len(titleWords) {
-		titleLen = len(titleWords)
+	// Author, tag, archive and category paths render as listings with a
+	// fixed title; everything else is an article.
+	title := rt.title()
+	if rt.kind == articlePage {
+		title = page.gen.Title(page.rng, 4+page.rng.IntN(5))
+	}
+	if rt.page > 1 {
+		title = fmt.Sprintf("%s – Page %d", title, rt.page)
 	}
-	title := strings.Join(titleWords[:titleLen], " ")
 
 	// Generate page metadata
-	meta := GenerateMeta(title, generatedText, r.URL.Path)
+	meta := page.GenerateMeta(title, lead, canonical.Path)
+	meta.Type = rt.schemaType()
+	if rt.page > 1 {
+		meta.Prev = pageURL(basePath, rt.page-1)
+	}
+	if rt.page < totalPages {
+		meta.Next = pageURL(basePath, rt.page+1)
+	}
+	byline := RenderByline(meta)
+	if rt.kind != articlePage {
+		byline = ""
+	}
+
+	// The page budget is cut down to what is left of the session's.
+	budget := configuredBudget()
+	if elapsed, written, err := rc.StreamUsage(r); err != nil {
+		log.Printf("failed to get stream usage: %s", err.Error())
+	} else {
+		budget = budget.forSession(elapsed, written)
+	}
+
+	// Stream main content slowly until the budget runs out.
+	// Canaries are woven in after degrading so they always stay intact.
+	// Only the first page opens with the lead; later pages carry on.
+	text := article.gen.Stream(article.rng)
+	if rt.page <= 1 {
+		text = prepend(lead, text)
+	}
+	text = degrade(text, article.rng, page.degrade)
+	var body iter.Seq[bodyToken]
+	if rt.kind == articlePage {
+		body = article.structureBody(weaveCanary(text, canary))
+		body = article.linkNouns(body, erebusconfig.Conf.InlineLinkDensity, canary)
+	} else {
+		body = article.listing(rt, totalPages, weaveCanary(text, canary))
+	}
+
+	if f != formatHTML && f != formatAMP {
+		alt := alternate{format: f, meta: meta, lang: page.lang, article: rt.kind == articlePage}
+		sink := alt.writeHeader(w)
+		flusher.Flush()
+		streamBody(rc, sink, flusher, responseController, r, body, budget)
+		// Sections and links are drawn in the same order as for HTML,
+		// so they match the HTML page.
+		if alt.article {
+			alt.sections = page.GenerateSections(2 + page.rng.IntN(3))
+			alt.related = page.GenerateLinks(8 + page.rng.IntN(5))
+		}
+		alt.writeFooter(w)
+		flusher.Flush()
+		return
+	}
 
 	ts, err := template.ParseFiles("./html/pages/manifest.tmpl")
 	if err != nil {
@@ -68,18 +166,23 @@ func generateHandler(rc *session.Client, w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	head := meta.RenderHead() + renderAlternates(canonical, f) + renderFeedLink(canonical.Path)
 	data := struct {
+		Lang           string
+		AMP            bool
 		Title          string
 		MetaHTML       template.HTML
 		NavHTML        template.HTML
 		BreadcrumbHTML template.HTML
 		BylineHTML     template.HTML
 	}{
+		Lang:           page.lang,
+		AMP:            f == formatAMP,
 		Title:          title,
-		MetaHTML:       template.HTML(meta.RenderHead()),                                  //nolint:gosec
-		NavHTML:        template.HTML(RenderNav(GenerateNavLinks())),                      //nolint:gosec
-		BreadcrumbHTML: template.HTML(RenderBreadcrumbs(GenerateBreadcrumbs(r.URL.Path))), //nolint:gosec
-		BylineHTML:     template.HTML(RenderByline(meta)),                                 //nolint:gosec
+		MetaHTML:       template.HTML(head),                                                   //nolint:gosec
+		NavHTML:        template.HTML(RenderNav(GenerateNavLinks())),                          //nolint:gosec
+		BreadcrumbHTML: template.HTML(RenderBreadcrumbs(GenerateBreadcrumbs(canonical.Path))), //nolint:gosec
+		BylineHTML:     template.HTML(byline),                                                 //nolint:gosec
 	}
 	_ = responseController.SetWriteDeadline(time.Now().Add(30 * time.Second))
 	err = ts.Execute(w, data)
@@ -90,48 +193,40 @@ func generateHandler(rc *session.Client, w http.ResponseWriter, r *http.Request)
 	}
 	flusher.Flush()
 
-	// Stream main content slowly
-	streamWords(w, flusher, responseController, r, strings.Fields(generatedText),
-		erebusconfig.Conf.StreamInterval)
+	streamBody(rc, newBodyWriter(w), flusher, responseController, r, body, budget)
 
-	// Close the streamed paragraph and text div
-	_, _ = fmt.Fprint(w, `</p></div>`)
+	// Close the text div
+	_, _ = fmt.Fprint(w, `</div>`)
 	flusher.Flush()
 
-	// Generate and write sub-sections with headings
-	sectionCount := 2 + rand.IntN(3) //nolint:gosec
-	sections := GenerateSections(sectionCount)
-	_, _ = fmt.Fprint(w, RenderSections(sections))
-	flusher.Flush()
+	if rt.kind == articlePage {
+		// Generate and write sub-sections with headings
+		sectionCount := 2 + page.rng.IntN(3)
+		sections := page.GenerateSections(sectionCount)
+		_, _ = fmt.Fprint(w, RenderSections(sections))
+		flusher.Flush()
 
-	// Article links
-	articleLinks := GenerateLinks(8 + rand.IntN(5)) //nolint:gosec
-	_, _ = fmt.Fprint(w, `<ul class="article-links">`)
-	for _, l := range articleLinks {
-		_, _ = fmt.Fprintf(w, `<li><a href="%s">%s</a></li>`,
-			l.URL, html.EscapeString(l.Text))
+		// Article links
+		articleLinks := page.GenerateLinks(8 + page.rng.IntN(5))
+		_, _ = fmt.Fprint(w, `<ul class="article-links">`)
+		for _, l := range articleLinks {
+			_, _ = fmt.Fprintf(w, `<li><a href="%s">%s</a></li>`,
+				l.URL, html.EscapeString(l.Text))
+		}
+		_, _ = fmt.Fprint(w, `</ul>`)
+		flusher.Flush()
 	}
-	_, _ = fmt.Fprint(w, `</ul>`)
-	flusher.Flush()
 
 	// Pagination
-	basePath := r.URL.Path
-	if basePath == "/" {
-		basePath = "/articles"
-	}
-	pagination := GeneratePaginationLinks(basePath)
-	_, _ = fmt.Fprint(w, `<nav class="pagination">`)
-	for _, p := range pagination {
-		_, _ = fmt.Fprintf(w, `<a href="%s">%s</a>`, p.URL, html.EscapeString(p.Text))
-	}
-	_, _ = fmt.Fprint(w, `</nav>`)
+	pagination := GeneratePaginationLinks(basePath, rt.page, totalPages)
+	_, _ = fmt.Fprint(w, RenderPagination(pagination, rt.page))
 
 	// Close content div
 	_, _ = fmt.Fprint(w, `</div>`)
 	flusher.Flush()
 
 	// Sidebar
-	sidebarLinks := GenerateLinks(5 + rand.IntN(3)) //nolint:gosec
+	sidebarLinks := page.GenerateLinks(5 + page.rng.IntN(3))
 	_, _ = fmt.Fprint(w, RenderSidebar(sidebarLinks))
 
 	// Close layout div
@@ -139,51 +234,142 @@ func generateHandler(rc *session.Client, w http.ResponseWriter, r *http.Request)
 	flusher.Flush()
 
 	// Footer
-	footerLinks := GenerateLinks(8 + rand.IntN(4)) //nolint:gosec
-	_, _ = fmt.Fprint(w, RenderFooter(footerLinks))
+	footerLinks := page.GenerateLinks(8 + page.rng.IntN(4))
+	_, _ = fmt.Fprint(w, RenderFooter(footerLinks, page.GenerateAuthorName()))
 
 	_, _ = fmt.Fprint(w, `</body></html>`)
 	flusher.Flush()
 }
 
-func streamWords(w http.ResponseWriter, flusher http.Flusher,
-	rc *http.ResponseController, r *http.Request,
-	words []string, intervalSeconds float64) {
-	i := 0
-	for i < len(words) {
-		select {
-		case <-r.Context().Done():
+// defaultStreamSeconds is the time budget when StreamSeconds is unset.
+const defaultStreamSeconds = 120
+
+// streamBudget bounds how long and how much the body of one page streams.
+type streamBudget struct {
+	duration time.Duration
+	// bytes is the most body text to send, zero for no limit.
+	bytes int
+}
+
+// configuredBudget returns the stream budget from the config.
+func configuredBudget() streamBudget {
+	seconds := erebusconfig.Conf.StreamSeconds
+	if seconds <= 0 {
+		seconds = defaultStreamSeconds
+	}
+	return streamBudget{
+		duration: time.Duration(seconds * float64(time.Second)),
+		bytes:    erebusconfig.Conf.StreamBytes,
+	}
+}
+
+// forSession narrows b to what is left of the session budget after the
+// session streamed for elapsed and was sent written bytes. When nothing
+// is left the budget is spent from the start.
+func (b streamBudget) forSession(elapsed time.Duration, written int64) streamBudget {
+	if seconds := erebusconfig.Conf.SessionStreamSeconds; seconds > 0 {
+		left := time.Duration(seconds*float64(time.Second)) - elapsed
+		b.duration = max(min(b.duration, left), 0)
+	}
+	if limit := int64(erebusconfig.Conf.SessionStreamBytes); limit > 0 {
+		left := limit - written
+		switch {
+		case left <= 0:
+			b.duration = 0
+		case b.bytes == 0 || left < int64(b.bytes):
+			b.bytes = int(left)
+		}
+	}
+	return b
+}
+
+// spent reports whether streaming for elapsed and sending written bytes
+// used up the budget.
+func (b streamBudget) spent(elapsed time.Duration, written int) bool {
+	return elapsed >= b.duration || (b.bytes > 0 && written >= b.bytes)
+}
+
+// prepend yields first and then everything in seq.
+func prepend(first string, seq iter.Seq[string]) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		if !yield(first) {
 			return
-		default:
-			if err := rc.SetWriteDeadline(time.Now().Add(30 * time.Second)); err != nil {
+		}
+		for s := range seq {
+			if !yield(s) {
 				return
 			}
-			chunkSize := 1 + rand.IntN(8) //nolint:gosec
-			if i+chunkSize > len(words) {
-				chunkSize = len(words) - i
-			}
+		}
+	}
+}
 
-			chunk := words[i : i+chunkSize]
-			_, _ = fmt.Fprint(w, html.EscapeString(strings.Join(chunk, " "))+" ")
-			flusher.Flush()
+// streamBody streams the body with streamWords and adds the time and
+// bytes it took to the session's stream usage.
+func streamBody(rc *session.Client, sink tokenSink, flusher http.Flusher,
+	responseController *http.ResponseController, r *http.Request,
+	body iter.Seq[bodyToken], budget streamBudget) {
+	start := time.Now()
+	streamWords(sink, flusher, responseController, r, body,
+		erebusconfig.Conf.StreamInterval, budget)
+	if err := rc.AddStreamUsage(r, time.Since(start), sink.size()); err != nil {
+		log.Printf("failed to store stream usage: %s", err.Error())
+	}
+}
 
-			i += chunkSize
+// streamWords writes the body tokens to sink with a delay after every few
+// words, until the client goes away, the tokens run out or the budget is
+// spent. Elements still open at that point are closed, so the page stays
+// well-formed.
+func streamWords(sink tokenSink, flusher http.Flusher,
+	rc *http.ResponseController, r *http.Request,
+	tokens iter.Seq[bodyToken], intervalSeconds float64, budget streamBudget) {
+	defer func() {
+		_ = sink.Close()
+		flusher.Flush()
+	}()
 
-			var delay time.Duration
-			if intervalSeconds > 0 {
-				delay = time.Duration(intervalSeconds * float64(time.Second))
-			} else {
-				// Use random delays if no interval is set
-				if rand.Float32() < 0.15 { //nolint:gosec
-					delay = time.Duration(300+rand.IntN(200)) * time.Millisecond //nolint:gosec
-				} else {
-					delay = time.Duration(20+rand.IntN(180)) * time.Millisecond //nolint:gosec
-				}
+	next, stop := iter.Pull(tokens)
+	defer stop()
+
+	start := time.Now()
+	for !budget.spent(time.Since(start), sink.size()) {
+		if err := rc.SetWriteDeadline(time.Now().Add(30 * time.Second)); err != nil {
+			return
+		}
+		// Chunking and delays only shape the timing, not the content,
+		// so they stay on the global source rather than the page RNG.
+		// Markup is written as it comes and doesn't count towards a chunk.
+		chunkSize := 1 + rand.IntN(8) //nolint:gosec
+		for words := 0; words < chunkSize; {
+			token, ok := next()
+			if !ok {
+				return
+			}
+			if err := sink.write(token); err != nil {
+				return
 			}
+			if token.word != "" {
+				words++
+			}
+		}
+		flusher.Flush()
 
-			if i < len(words) {
-				time.Sleep(delay)
+		var delay time.Duration
+		if intervalSeconds > 0 {
+			delay = time.Duration(intervalSeconds * float64(time.Second))
+		} else {
+			// Use random delays if no interval is set
+			if rand.Float32() < 0.15 { //nolint:gosec
+				delay = time.Duration(300+rand.IntN(200)) * time.Millisecond //nolint:gosec
+			} else {
+				delay = time.Duration(20+rand.IntN(180)) * time.Millisecond //nolint:gosec
 			}
 		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-time.After(delay):
+		}
 	}
 }
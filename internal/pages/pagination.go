@@ -0,0 +1,131 @@
+package pages
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"html"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"Erebus/internal/erebusconfig"
+)
+
+// Page counts are drawn from these ranges. Listings are made very deep
+// so crawlers walking them never reach the end; articles are split over
+// a handful of pages like a long read would be.
+const (
+	minListingPages = 500
+	maxListingPages = 50000
+	minArticlePages = 2
+	maxArticlePages = 30
+)
+
+// pageParam returns the page number requested through ?page=, or 1 when
+// it is missing or not a positive number.
+func pageParam(u *url.URL) int {
+	n, err := strconv.Atoi(u.Query().Get("page"))
+	if err != nil || n < 1 {
+		return 1
+	}
+	return n
+}
+
+// pageCount returns how many pages there are under basePath. It is
+// derived from the site secret and the path alone, so every page of a
+// sequence agrees on where it ends.
+func pageCount(basePath string, kind pageKind) int {
+	n := secretNumber("pages:" + basePath)
+	if kind == articlePage {
+		return minArticlePages + n%(maxArticlePages-minArticlePages+1)
+	}
+	return minListingPages + n%(maxListingPages-minListingPages+1)
+}
+
+// secretNumber derives a non-negative number from the site secret and
+// label, for counts that must not change between requests.
+func secretNumber(label string) int {
+	h := sha256.New()
+	h.Write([]byte(erebusconfig.Conf.SiteSecret))
+	h.Write([]byte{0})
+	h.Write([]byte(label))
+	return int(binary.LittleEndian.Uint64(h.Sum(nil)) >> 1) //nolint:gosec // shifted into range
+}
+
+// pageURL returns the URL of page n under basePath. The first page is
+// the base path itself, without a query. basePath comes from the request,
+// so it is escaped.
+func pageURL(basePath string, n int) string {
+	escaped := escapePath(basePath)
+	if n <= 1 {
+		return escaped
+	}
+	return fmt.Sprintf("%s?page=%d", escaped, n)
+}
+
+// escapePath escapes a decoded request path, or one built from it, for
+// use in a URL.
+func escapePath(path string) string {
+	return (&url.URL{Path: path}).EscapedPath()
+}
+
+// GeneratePaginationLinks creates previous and next links around the page
+// numbers near current, plus the first and last page. Gaps in the
+// sequence are marked by links without a URL.
+func GeneratePaginationLinks(basePath string, current, total int) []Link {
+	var links []Link
+	if current > 1 {
+		links = append(links, Link{URL: pageURL(basePath, current-1), Text: "Previous"})
+	}
+
+	start := max(1, current-2)
+	end := min(total, current+2)
+	if start > 1 {
+		links = append(links, Link{URL: pageURL(basePath, 1), Text: "1"})
+		if start > 2 {
+			links = append(links, Link{Text: "…"})
+		}
+	}
+	for n := start; n <= end; n++ {
+		links = append(links, Link{URL: pageURL(basePath, n), Text: strconv.Itoa(n)})
+	}
+	if end < total {
+		if end < total-1 {
+			links = append(links, Link{Text: "…"})
+		}
+		links = append(links, Link{URL: pageURL(basePath, total), Text: strconv.Itoa(total)})
+	}
+
+	if current < total {
+		links = append(links, Link{URL: pageURL(basePath, current+1), Text: "Next"})
+	}
+	return links
+}
+
+// RenderPagination returns HTML for pagination links, marking the
+// current page and the gaps.
+func RenderPagination(links []Link, current int) string {
+	var b strings.Builder
+	b.WriteString(`<nav class="pagination">`)
+	for _, l := range links {
+		switch {
+		case l.URL == "":
+			b.WriteString(fmt.Sprintf(`<span class="gap">%s</span>`, html.EscapeString(l.Text)))
+		case l.Text == strconv.Itoa(current):
+			b.WriteString(fmt.Sprintf(`<a href="%s" class="current" aria-current="page">%s</a>`,
+				html.EscapeString(l.URL), html.EscapeString(l.Text)))
+		default:
+			rel := ""
+			if l.Text == "Previous" {
+				rel = ` rel="prev"`
+			} else if l.Text == "Next" {
+				rel = ` rel="next"`
+			}
+			b.WriteString(fmt.Sprintf(`<a href="%s"%s>%s</a>`,
+				html.EscapeString(l.URL), rel, html.EscapeString(l.Text)))
+		}
+	}
+	b.WriteString(`</nav>`)
+	return b.String()
+}
@@ -0,0 +1,24 @@
+package pages
+
+import "testing"
+
+func TestPageURL(t *testing.T) {
+	tests := []struct {
+		basePath string
+		n        int
+		want     string
+	}{
+		{"/news", 0, "/news"},
+		{"/news", 1, "/news"},
+		{"/news", 2, "/news?page=2"},
+		{"/", 3, "/?page=3"},
+		{"/tag/a b?c", 1, "/tag/a%20b%3Fc"},
+		{"/tag/a b?c", 2, "/tag/a%20b%3Fc?page=2"},
+		{`/tag/"><script>`, 4, "/tag/%22%3E%3Cscript%3E?page=4"},
+	}
+	for _, tt := range tests {
+		if got := pageURL(tt.basePath, tt.n); got != tt.want {
+			t.Errorf("pageURL(%q, %d) = %q, want %q", tt.basePath, tt.n, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package pages
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// pageKind is the layout a URL renders with.
+type pageKind int
+
+const (
+	articlePage pageKind = iota
+	authorPage
+	tagPage
+	archivePage
+	categoryPage
+)
+
+// route is what a request path asks for, in the URL patterns that
+// generateOneLink invents.
+type route struct {
+	kind pageKind
+	// name is the author slug, tag or category slug the page lists.
+	name string
+	// year and month narrow an archive; month is zero for a whole year.
+	year  int
+	month time.Month
+	// page is the requested page of the sequence, starting at 1.
+	page int
+}
+
+// parseRoute recognizes author, tag, archive and category index paths.
+// Everything else, including unknown patterns, is an article.
+func parseRoute(path string) route {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	switch {
+	case len(parts) == 2 && parts[0] == "author" && parts[1] != "":
+		return route{kind: authorPage, name: parts[1]}
+	case len(parts) == 2 && parts[0] == "tag" && parts[1] != "":
+		return route{kind: tagPage, name: parts[1]}
+	case len(parts) == 1 && slices.Contains(categories, parts[0]):
+		return route{kind: categoryPage, name: parts[0]}
+	case (len(parts) == 2 || len(parts) == 3) && parts[0] == "archive":
+		year, err := strconv.Atoi(parts[1])
+		if err != nil || year < 1900 || year > 2100 {
+			break
+		}
+		rt := route{kind: archivePage, year: year}
+		if len(parts) == 3 {
+			if rt.month = parseMonth(parts[2]); rt.month == 0 {
+				break
+			}
+		}
+		return rt
+	}
+	return route{kind: articlePage}
+}
+
+// parseMonth accepts a month as a lowercase name or a number, returning
+// zero when s is neither.
+func parseMonth(s string) time.Month {
+	if i := slices.Index(months, s); i >= 0 {
+		return time.Month(i + 1)
+	}
+	if n, err := strconv.Atoi(s); err == nil && n >= 1 && n <= 12 {
+		return time.Month(n)
+	}
+	return 0
+}
+
+// authorName turns an author slug back into a display name.
+func authorName(slug string) string {
+	return titleCase(strings.ReplaceAll(slug, "-", " "))
+}
+
+// authorSlug is the inverse of authorName.
+func authorSlug(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, " ", "-"))
+}
@@ -0,0 +1,42 @@
+package pages
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRoute(t *testing.T) {
+	tests := []struct {
+		path string
+		want route
+	}{
+		{"/", route{kind: articlePage}},
+		{"/news/strike", route{kind: articlePage}},
+		{"/articles/2024/03/strike", route{kind: articlePage}},
+
+		{"/news", route{kind: categoryPage, name: "news"}},
+		{"/news/", route{kind: categoryPage, name: "news"}},
+		{"/newsroom", route{kind: articlePage}},
+
+		{"/author/rosa-luxemburg", route{kind: authorPage, name: "rosa-luxemburg"}},
+		{"/author/", route{kind: articlePage}},
+		{"/author/rosa-luxemburg/posts", route{kind: articlePage}},
+
+		{"/tag/labour", route{kind: tagPage, name: "labour"}},
+		{"/tag/a b?c", route{kind: tagPage, name: "a b?c"}},
+		{"/tag/", route{kind: articlePage}},
+
+		{"/archive/2024", route{kind: archivePage, year: 2024}},
+		{"/archive/2024/march", route{kind: archivePage, year: 2024, month: time.March}},
+		{"/archive/2024/03", route{kind: archivePage, year: 2024, month: time.March}},
+		{"/archive/2024/13", route{kind: articlePage}},
+		{"/archive/2024/marchx", route{kind: articlePage}},
+		{"/archive/1800", route{kind: articlePage}},
+		{"/archive/next", route{kind: articlePage}},
+	}
+	for _, tt := range tests {
+		if got := parseRoute(tt.path); got != tt.want {
+			t.Errorf("parseRoute(%q) = %+v, want %+v", tt.path, got, tt.want)
+		}
+	}
+}
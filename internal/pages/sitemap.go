@@ -1,87 +1,239 @@
 package pages
 
 import (
+	"compress/gzip"
 	"encoding/xml"
 	"fmt"
-	"math/rand/v2"
+	"io"
+	"log"
 	"net/http"
-	"time"
+	"strconv"
+	"strings"
 )
 
+// Sitemap kinds and how many URLs each of their sitemaps lists. The
+// number of sitemaps is kept within the 50,000 an index may list.
+const (
+	postsSitemap = "posts"
+	newsSitemap  = "news"
+
+	postsPerSitemap = 500
+	newsPerSitemap  = 100
+
+	minPostSitemaps = 1000
+	maxNewsSitemaps = 3
+	maxPostSitemaps = 50000 - maxNewsSitemaps
+)
+
+type sitemapIndex struct {
+	XMLName  xml.Name       `xml:"sitemapindex"`
+	XMLNS    string         `xml:"xmlns,attr"`
+	Sitemaps []sitemapEntry `xml:"sitemap"`
+}
+
+type sitemapEntry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod"`
+}
+
 type urlSet struct {
-	XMLName xml.Name  `xml:"urlset"`
-	XMLNS   string    `xml:"xmlns,attr"`
-	URLs    []siteURL `xml:"url"`
+	XMLName    xml.Name  `xml:"urlset"`
+	XMLNS      string    `xml:"xmlns,attr"`
+	XMLNSImage string    `xml:"xmlns:image,attr,omitempty"`
+	XMLNSNews  string    `xml:"xmlns:news,attr,omitempty"`
+	URLs       []siteURL `xml:"url"`
 }
 
 type siteURL struct {
-	Loc        string `xml:"loc"`
-	LastMod    string `xml:"lastmod"`
-	ChangeFreq string `xml:"changefreq"`
-	Priority   string `xml:"priority"`
+	Loc        string       `xml:"loc"`
+	LastMod    string       `xml:"lastmod"`
+	ChangeFreq string       `xml:"changefreq,omitempty"`
+	Priority   string       `xml:"priority,omitempty"`
+	Images     []imageEntry `xml:"image:image"`
+	News       *newsEntry   `xml:"news:news"`
+}
+
+type imageEntry struct {
+	Loc string `xml:"image:loc"`
+}
+
+type newsEntry struct {
+	Publication     newsPublication `xml:"news:publication"`
+	PublicationDate string          `xml:"news:publication_date"`
+	Title           string          `xml:"news:title"`
+}
+
+type newsPublication struct {
+	Name     string `xml:"news:name"`
+	Language string `xml:"news:language"`
+}
+
+// sitemapCount returns how many sitemaps of kind there are. Like page
+// counts, it only depends on the site secret.
+func sitemapCount(kind string) int {
+	n := secretNumber("sitemaps:" + kind)
+	if kind == newsSitemap {
+		return 1 + n%maxNewsSitemaps
+	}
+	return minPostSitemaps + n%(maxPostSitemaps-minPostSitemaps+1)
+}
+
+// parseSitemap recognizes child sitemap paths such as
+// /sitemaps/posts-12.xml.gz, returning the kind, the number and whether
+// the file is gzipped. Numbers past the count of the kind don't exist.
+func parseSitemap(path string) (string, int, bool, bool) {
+	name, ok := strings.CutPrefix(path, "/sitemaps/")
+	if !ok {
+		return "", 0, false, false
+	}
+	name, gz := strings.CutSuffix(name, ".gz")
+	name, ok = strings.CutSuffix(name, ".xml")
+	if !ok {
+		return "", 0, false, false
+	}
+	kind, num, ok := strings.Cut(name, "-")
+	if !ok || (kind != postsSitemap && kind != newsSitemap) {
+		return "", 0, false, false
+	}
+	n, err := strconv.Atoi(num)
+	if err != nil || n < 1 || n > sitemapCount(kind) {
+		return "", 0, false, false
+	}
+	return kind, n, gz, true
 }
 
-// SitemapHandler dynamically generates a sitemap.xml with realistic URLs.
-// Each request produces different URLs, creating an infinite crawl surface.
+// SitemapHandler serves /sitemap.xml as a sitemap index pointing to the
+// gzipped child sitemaps, news first. There are tens of thousands of
+// post sitemaps, so a crawler that trusts them never runs out of URLs.
 func SitemapHandler(w http.ResponseWriter, r *http.Request) {
-	scheme := "https"
-	if r.TLS == nil {
-		scheme = "http"
+	page := NewPage(r)
+	origin := requestOrigin(r)
+
+	index := sitemapIndex{XMLNS: "http://www.sitemaps.org/schemas/sitemap/0.9"}
+	for _, kind := range []string{newsSitemap, postsSitemap} {
+		for n := range sitemapCount(kind) {
+			index.Sitemaps = append(index.Sitemaps, sitemapEntry{
+				Loc:     fmt.Sprintf("%s/sitemaps/%s-%d.xml.gz", origin, kind, n+1),
+				LastMod: page.GenerateDate().Format("2006-01-02"),
+			})
+		}
+	}
+	writeSitemap(w, r, index, false)
+}
+
+// ChildSitemapHandler serves the sitemaps the index points to. Post
+// sitemaps list generated URLs with their images, news sitemaps list
+// articles with Google News metadata. The URLs are seeded from the
+// request like any other page, so a sitemap stays the same on every fetch.
+func ChildSitemapHandler(w http.ResponseWriter, r *http.Request) {
+	kind, n, gz, ok := parseSitemap(r.URL.Path)
+	if !ok {
+		http.NotFound(w, r)
+		return
 	}
-	baseURL := fmt.Sprintf("%s://%s", scheme, r.Host)
-
-	// nolint:gosec //This rand package is fine
-	urlCount := 50 + rand.IntN(51) // 50-100 URLs
-	urls := make([]siteURL, 0, urlCount)
-
-	// Add the homepage
-	urls = append(urls, siteURL{
-		Loc:        baseURL + "/",
-		LastMod:    time.Now().Format("2006-01-02"),
-		ChangeFreq: "daily",
-		Priority:   "1.0",
-	})
-
-	// Add category index pages
-	for _, cat := range categories {
-		urls = append(urls, siteURL{
-			Loc:        fmt.Sprintf("%s/%s", baseURL, cat),
-			LastMod:    GenerateRecentDate().Format("2006-01-02"),
-			ChangeFreq: "weekly",
-			Priority:   "0.9",
+	page := NewPage(r)
+	origin := requestOrigin(r)
+
+	set := urlSet{XMLNS: "http://www.sitemaps.org/schemas/sitemap/0.9"}
+	if kind == newsSitemap {
+		set.XMLNSNews = "http://www.google.com/schemas/sitemap-news/0.9"
+		for range newsPerSitemap {
+			link := page.generateOneLink()
+			date := page.GenerateDate().Format("2006-01-02")
+			set.URLs = append(set.URLs, siteURL{
+				Loc:     origin + link.URL,
+				LastMod: date,
+				News: &newsEntry{
+					Publication:     newsPublication{Name: r.Host, Language: page.lang},
+					PublicationDate: date,
+					Title:           link.Text,
+				},
+			})
+		}
+		writeSitemap(w, r, set, gz)
+		return
+	}
+
+	set.XMLNSImage = "http://www.google.com/schemas/sitemap-image/1.1"
+	if n == 1 {
+		// The first sitemap starts with the homepage and the categories.
+		set.URLs = append(set.URLs, siteURL{
+			Loc:        origin + "/",
+			LastMod:    page.GenerateDate().Format("2006-01-02"),
+			ChangeFreq: "daily",
+			Priority:   "1.0",
 		})
+		for _, cat := range categories {
+			set.URLs = append(set.URLs, siteURL{
+				Loc:        fmt.Sprintf("%s/%s", origin, cat),
+				LastMod:    page.GenerateDate().Format("2006-01-02"),
+				ChangeFreq: "weekly",
+				Priority:   "0.9",
+			})
+		}
 	}
 
-	// Fill the rest with generated article URLs
 	freqs := []string{"daily", "weekly", "monthly"}
-	for len(urls) < urlCount {
-		link := generateOneLink()
-		//nolint:gosec // this rand package is fine
-		priority := fmt.Sprintf("%.1f", 0.5+rand.Float64()*0.4)
-
-		urls = append(urls, siteURL{
-			Loc:     baseURL + link.URL,
-			LastMod: GenerateRecentDate().Format("2006-01-02"),
-			//nolint:gosec // this rand package is fine
-			ChangeFreq: freqs[rand.IntN(len(freqs))],
-			Priority:   priority,
-		})
+	for len(set.URLs) < postsPerSitemap {
+		link := page.generateOneLink()
+		date := page.GenerateDate()
+		u := siteURL{
+			Loc:        origin + link.URL,
+			LastMod:    date.Format("2006-01-02"),
+			ChangeFreq: freqs[page.rng.IntN(len(freqs))],
+			Priority:   fmt.Sprintf("%.1f", 0.5+page.rng.Float64()*0.4),
+		}
+		for range page.rng.IntN(3) {
+			u.Images = append(u.Images, imageEntry{
+				Loc: fmt.Sprintf("%s/images/%d/%02d/%s.jpg", origin, date.Year(), date.Month(), page.GenerateSlug(2)),
+			})
+		}
+		set.URLs = append(set.URLs, u)
 	}
+	writeSitemap(w, r, set, gz)
+}
 
-	sitemap := urlSet{
-		XMLNS: "http://www.sitemaps.org/schemas/sitemap/0.9",
-		URLs:  urls,
+// writeSitemap encodes doc as XML, compressed. A .gz file is sent as
+// gzip data; anything else is gzip-encoded for clients that accept it.
+func writeSitemap(w http.ResponseWriter, r *http.Request, doc any, gz bool) {
+	var out io.Writer = w
+	compress := gz || acceptsGzip(r)
+	if gz {
+		w.Header().Set("Content-Type", "application/gzip")
+	} else {
+		w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+		w.Header().Set("Vary", "Accept-Encoding")
+		if compress {
+			w.Header().Set("Content-Encoding", "gzip")
+		}
+	}
+	if compress {
+		zw := gzip.NewWriter(w)
+		defer func() {
+			if err := zw.Close(); err != nil {
+				log.Printf("error compressing sitemap: %s", err.Error())
+			}
+		}()
+		out = zw
 	}
 
-	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
-	_, err := w.Write([]byte(xml.Header))
-	if err != nil {
-		_ = fmt.Errorf("error: %w", err)
+	if _, err := io.WriteString(out, xml.Header); err != nil {
+		return
 	}
-	enc := xml.NewEncoder(w)
+	enc := xml.NewEncoder(out)
 	enc.Indent("", "  ")
-	err = enc.Encode(sitemap)
-	if err != nil {
-		_ = fmt.Errorf("error: %w", err)
+	if err := enc.Encode(doc); err != nil {
+		log.Printf("error encoding sitemap: %s", err.Error())
+	}
+}
+
+// acceptsGzip reports whether r accepts a gzip-encoded response.
+func acceptsGzip(r *http.Request) bool {
+	// Accept-Encoding has the same syntax as Accept-Language.
+	for _, coding := range parseAcceptLanguage(r.Header.Get("Accept-Encoding")) {
+		if strings.EqualFold(coding.tag, "gzip") || coding.tag == "*" {
+			return true
+		}
 	}
+	return false
 }
@@ -3,10 +3,10 @@ package pages
 import (
 	"fmt"
 	"html"
-	"math/rand/v2"
 	"strings"
 
 	"Erebus/internal/bable"
+	"Erebus/internal/codegen"
 )
 
 // Breadcrumb represents a single breadcrumb navigation item.
@@ -20,6 +20,10 @@ type Section struct {
 	Heading string
 	Content string
 	Items   []string // optional list items
+	Table   *Table   // optional data table
+	// Code is an optional snippet in CodeLang, one of codegen.Languages.
+	Code     string
+	CodeLang string
 }
 
 // GenerateBreadcrumbs builds a breadcrumb trail from the URL path.
@@ -74,39 +78,65 @@ func RenderNav(links []Link) string {
 }
 
 // GenerateSections produces sub-sections with headings and text.
-func GenerateSections(count int) []Section {
+func (p *Page) GenerateSections(count int) []Section {
 	sections := make([]Section, 0, count)
 	for range count {
-		heading := bable.Bable(1, 1)
-		// Trim to first few words for a heading
-		words := strings.Fields(heading)
-		if len(words) > 6 {
-			words = words[:6]
+		heading := p.gen.Title(p.rng, 6)
+		content := p.gen.Sentences(p.rng, 3+p.rng.IntN(5))
+		// 40% chance of citing a figure
+		if p.rng.Float32() < 0.4 {
+			content += " " + p.GenerateStatistic()
 		}
-		heading = strings.Join(words, " ")
-
-		content := bable.Bable(3+rand.IntN(5), 3)  //nolint:gosec
 
 		var items []string
 		// 30% chance of having a list
-		if rand.Float32() < 0.3 {  //nolint:gosec
-			listLen := 3 + rand.IntN(4)  //nolint:gosec
+		if p.rng.Float32() < 0.3 {
+			listLen := 3 + p.rng.IntN(4)
 			for range listLen {
-				items = append(items, bable.Bable(1, 2))
+				items = append(items, p.gen.ListItem(p.rng))
 			}
 		}
 
+		var table *Table
+		// 30% chance of a data table
+		if p.rng.Float32() < 0.3 {
+			t := p.GenerateTable()
+			table = &t
+		}
+
+		var code, codeLang string
+		// 25% chance of a code sample, named after words from the section
+		if p.rng.Float32() < 0.25 {
+			langs := codegen.Languages()
+			codeLang = langs[p.rng.IntN(len(langs))]
+			code = codegen.Snippet(p.rng, codeLang, contentWords(heading+" "+content))
+		}
+
 		sections = append(sections, Section{
-			Heading: heading,
-			Content: content,
-			Items:   items,
+			Heading:  heading,
+			Content:  content,
+			Items:    items,
+			Table:    table,
+			Code:     code,
+			CodeLang: codeLang,
 		})
 	}
 	return sections
 }
 
+// contentWords returns the lowercased words of text that aren't stop words.
+func contentWords(text string) []string {
+	var words []string
+	for _, w := range strings.Fields(text) {
+		if cleaned := stripNonAlpha(w); cleaned != "" && !bable.IsStopWord(cleaned) {
+			words = append(words, cleaned)
+		}
+	}
+	return words
+}
+
 // RenderSections returns streamed HTML for sub-sections
-// (headings, paragraphs, optional lists).
+// (headings, paragraphs, optional lists, tables and code samples).
 func RenderSections(sections []Section) string {
 	var b strings.Builder
 	for _, s := range sections {
@@ -119,6 +149,13 @@ func RenderSections(sections []Section) string {
 			}
 			b.WriteString(`</ul>`)
 		}
+		if s.Table != nil {
+			b.WriteString(RenderTable(*s.Table))
+		}
+		if s.Code != "" {
+			b.WriteString(fmt.Sprintf(`<pre><code class="language-%s">%s</code></pre>`,
+				s.CodeLang, html.EscapeString(s.Code)))
+		}
 	}
 	return b.String()
 }
@@ -135,8 +172,9 @@ func RenderSidebar(links []Link) string {
 	return b.String()
 }
 
-// RenderFooter returns HTML for the page footer with topic links.
-func RenderFooter(links []Link) string {
+// RenderFooter returns HTML for the page footer with topic links
+// and a copyright line for owner.
+func RenderFooter(links []Link, owner string) string {
 	var b strings.Builder
 	b.WriteString(`<footer class="site-footer"><div class="footer-links"><h4>Popular Topics</h4><ul>`)
 	for _, l := range links {
@@ -145,7 +183,7 @@ func RenderFooter(links []Link) string {
 	}
 	b.WriteString(`</ul></div>`)
 	b.WriteString(fmt.Sprintf(`<p class="copyright">%s. All rights reserved.</p>`,
-		html.EscapeString(GenerateAuthorName())))
+		html.EscapeString(owner)))
 	b.WriteString(`</footer>`)
 	return b.String()
 }
@@ -153,7 +191,7 @@ func RenderFooter(links []Link) string {
 // RenderByline returns an author/date byline.
 func RenderByline(meta PageMeta) string {
 	return fmt.Sprintf(`<div class="byline">By <a href="/author/%s">%s</a> | Published %s</div>`,
-		strings.ToLower(strings.ReplaceAll(meta.Author, " ", "-")),
+		authorSlug(meta.Author),
 		html.EscapeString(meta.Author),
 		meta.DateStr,
 	)
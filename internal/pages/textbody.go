@@ -0,0 +1,248 @@
+package pages
+
+import (
+	"encoding/json"
+	"html"
+	"io"
+	"strings"
+)
+
+// tokenSink is where streamWords writes the body tokens.
+type tokenSink interface {
+	write(token bodyToken) error
+	// Close finishes whatever the stream left open.
+	Close() error
+	// size returns the number of bytes written so far.
+	size() int
+}
+
+func (bw *bodyWriter) size() int {
+	return bw.written
+}
+
+// markdownEscaper escapes the characters generated words could use to
+// start Markdown markup.
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`, "`", "\\`",
+)
+
+// emphasis maps inline emphasis elements to their Markdown markers.
+var emphasis = map[string]string{"em": "*", "strong": "**"}
+
+// textWriter writes body tokens as Markdown, or as plain text with the
+// markup dropped. It is the counterpart of bodyWriter for the alternate
+// formats and likewise keeps the output well-formed however early the
+// stream ends.
+type textWriter struct {
+	w        io.Writer
+	markdown bool
+	open     []string
+	// hrefs holds the targets of the open links.
+	hrefs   []string
+	written int
+	// atStart is set where the next word needs no space in front of it.
+	atStart bool
+	// quoted is set inside a blockquote.
+	quoted bool
+	// cells counts the cells of the current table row, and header is set
+	// while the first row of a Markdown table is open.
+	cells  int
+	header bool
+}
+
+func newTextWriter(w io.Writer, markdown bool) *textWriter {
+	return &textWriter{w: w, markdown: markdown, atStart: true}
+}
+
+func (tw *textWriter) size() int {
+	return tw.written
+}
+
+// write writes token, turning elements into their Markdown equivalent or
+// into line breaks.
+func (tw *textWriter) write(token bodyToken) error {
+	switch {
+	case token.closing:
+		return tw.closeTag()
+	case token.tag != "":
+		return tw.openTag(token.tag, token.attrs)
+	default:
+		if token.glue {
+			tw.atStart = true
+		}
+		return tw.word(token.word)
+	}
+}
+
+func (tw *textWriter) word(word string) error {
+	if tw.markdown {
+		word = markdownEscaper.Replace(word)
+	}
+	if !tw.atStart {
+		word = " " + word
+	}
+	tw.atStart = false
+	return tw.raw(word)
+}
+
+// block starts a new paragraph-level block with prefix in front of it.
+func (tw *textWriter) block(prefix string) error {
+	sep := ""
+	if tw.written > 0 {
+		sep = "\n\n"
+	}
+	if tw.quoted && tw.markdown {
+		prefix = "> " + prefix
+	}
+	tw.atStart = true
+	return tw.raw(sep + prefix)
+}
+
+func (tw *textWriter) openTag(tag, attrs string) error {
+	tw.open = append(tw.open, tag)
+	md := func(marker string) string {
+		return mdMarker(tw.markdown, marker)
+	}
+
+	switch tag {
+	case "a":
+		tw.hrefs = append(tw.hrefs, hrefOf(attrs))
+		if !tw.markdown {
+			return nil
+		}
+		s := "["
+		if !tw.atStart {
+			s = " ["
+		}
+		tw.atStart = true
+		return tw.raw(s)
+	case "em", "strong":
+		marker := md(emphasis[tag])
+		if !tw.atStart {
+			marker = " " + marker
+		}
+		tw.atStart = true
+		return tw.raw(marker)
+	case "h1":
+		return tw.block(md("# "))
+	case "h2":
+		return tw.block(md("## "))
+	case "h3", "h4":
+		return tw.block(md("### "))
+	case "blockquote":
+		tw.quoted = true
+		return nil
+	case "li":
+		tw.atStart = true
+		return tw.raw("\n- ")
+	case "table":
+		tw.header = true
+		return tw.block("")
+	case "tr":
+		tw.cells = 0
+		tw.atStart = true
+		switch {
+		case tw.markdown && tw.header:
+			// A blank line keeps the table from continuing the caption.
+			return tw.raw("\n\n|")
+		case tw.markdown:
+			return tw.raw("\n|")
+		}
+		return tw.raw("\n")
+	case "th", "td":
+		tw.cells++
+		tw.atStart = false
+		if !tw.markdown && tw.cells == 1 {
+			tw.atStart = true
+		}
+		return nil
+	case "ul", "article", "caption":
+		// The caption starts on the line the table block opened.
+		return nil
+	default:
+		return tw.block("")
+	}
+}
+
+func (tw *textWriter) closeTag() error {
+	if len(tw.open) == 0 {
+		return nil
+	}
+	tag := tw.open[len(tw.open)-1]
+	tw.open = tw.open[:len(tw.open)-1]
+
+	switch tag {
+	case "a":
+		href := tw.hrefs[len(tw.hrefs)-1]
+		tw.hrefs = tw.hrefs[:len(tw.hrefs)-1]
+		if tw.markdown {
+			return tw.raw("](" + href + ")")
+		}
+	case "em", "strong":
+		return tw.raw(mdMarker(tw.markdown, emphasis[tag]))
+	case "blockquote":
+		tw.quoted = false
+	case "th", "td":
+		if tw.markdown {
+			return tw.raw(" |")
+		}
+	case "tr":
+		// Markdown tables need a delimiter row after the header.
+		if tw.markdown && tw.header {
+			tw.header = false
+			return tw.raw("\n|" + strings.Repeat(" --- |", tw.cells))
+		}
+	}
+	return nil
+}
+
+// mdMarker returns marker in Markdown and nothing in plain text.
+func mdMarker(markdown bool, marker string) string {
+	if markdown {
+		return marker
+	}
+	return ""
+}
+
+func (tw *textWriter) raw(s string) error {
+	n, err := io.WriteString(tw.w, s)
+	tw.written += n
+	return err
+}
+
+// Close closes every element that is still open and ends the last line.
+func (tw *textWriter) Close() error {
+	for len(tw.open) > 0 {
+		if err := tw.closeTag(); err != nil {
+			return err
+		}
+	}
+	return tw.raw("\n")
+}
+
+// hrefOf extracts the href value from raw attributes.
+func hrefOf(attrs string) string {
+	_, rest, ok := strings.Cut(attrs, `href="`)
+	if !ok {
+		return ""
+	}
+	value, _, _ := strings.Cut(rest, `"`)
+	return html.UnescapeString(value)
+}
+
+// jsonStringWriter escapes everything written to it for the inside of a
+// JSON string. Writes must not split a UTF-8 sequence.
+type jsonStringWriter struct {
+	w io.Writer
+}
+
+func (jw jsonStringWriter) Write(p []byte) (int, error) {
+	quoted, err := json.Marshal(string(p))
+	if err != nil {
+		return 0, err
+	}
+	if _, err := jw.w.Write(quoted[1 : len(quoted)-1]); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
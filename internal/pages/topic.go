@@ -0,0 +1,47 @@
+package pages
+
+import (
+	"strings"
+	"unicode"
+
+	"Erebus/internal/bable"
+)
+
+// maxTopicSeeds bounds how many words of a path steer a page.
+const maxTopicSeeds = 5
+
+// structuralWords name parts of the site rather than a topic.
+var structuralWords = map[string]bool{
+	"tag": true, "tags": true, "category": true, "categories": true,
+	"author": true, "authors": true, "archive": true, "archives": true,
+	"page": true, "articles": true, "article": true, "blog": true,
+	"post": true, "posts": true, "feed": true, "amp": true, "index": true,
+	"html": true, "htm": true, "xml": true, "rss": true, "atom": true,
+	"sitemap": true, "sitemaps": true, "gz": true, "images": true, "jpg": true,
+}
+
+// topicSeeds extracts the words a page at path should be about, taking
+// them from the path's slugs and skipping numbers, stop words and the
+// names of site sections.
+func topicSeeds(path string) []string {
+	words := strings.FieldsFunc(strings.ToLower(path), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+
+	var seeds []string
+	seen := make(map[string]bool)
+	for _, word := range words {
+		if len([]rune(word)) < 3 || bable.IsStopWord(word) ||
+			structuralWords[word] || seen[word] {
+			continue
+		}
+		seen[word] = true
+		seeds = append(seeds, word)
+	}
+	// The last segments are the most specific, so they win when the
+	// path has more words than are used.
+	if len(seeds) > maxTopicSeeds {
+		seeds = seeds[len(seeds)-maxTopicSeeds:]
+	}
+	return seeds
+}
@@ -0,0 +1,54 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// StreamUsage returns how long the IP's current session has been streamed
+// page bodies and how many bytes of body text it was sent.
+func (c *Client) StreamUsage(r *http.Request) (time.Duration, int64, error) {
+	ip := r.Header.Get("CF-Connecting-IP")
+	key := fmt.Sprintf("trap:streamed:%s", ip)
+
+	values, err := c.Rdb.HMGet(c.Ctx, key, "ms", "bytes").Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		slog.Error("failed to get stream usage", "ip", ip, "error", err)
+		return 0, 0, fmt.Errorf("get stream usage: %w", err)
+	}
+
+	var ms, written int64
+	if len(values) == 2 {
+		// Missing fields come back as nil and count as nothing streamed.
+		if s, ok := values[0].(string); ok {
+			ms, _ = strconv.ParseInt(s, 10, 64)
+		}
+		if s, ok := values[1].(string); ok {
+			written, _ = strconv.ParseInt(s, 10, 64)
+		}
+	}
+	return time.Duration(ms) * time.Millisecond, written, nil
+}
+
+// AddStreamUsage adds one page's streaming time and bytes to the IP's
+// session totals. The totals expire with the session.
+func (c *Client) AddStreamUsage(r *http.Request, elapsed time.Duration, written int) error {
+	ip := r.Header.Get("CF-Connecting-IP")
+	key := fmt.Sprintf("trap:streamed:%s", ip)
+
+	pipe := c.Rdb.TxPipeline()
+	pipe.HIncrBy(c.Ctx, key, "ms", elapsed.Milliseconds())
+	pipe.HIncrBy(c.Ctx, key, "bytes", int64(written))
+	pipe.Expire(c.Ctx, key, ttlSet)
+	if _, err := pipe.Exec(c.Ctx); err != nil {
+		slog.Error("failed to add stream usage", "ip", ip, "error", err)
+		return fmt.Errorf("add stream usage: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrUnknownCanary indicates no session was ever given the canary.
+var ErrUnknownCanary = errors.New("unknown canary")
+
+// maxCanaryPaths bounds how many served paths are kept per canary.
+const maxCanaryPaths = 1000
+
+// CanaryRecord describes the session a canary was issued to.
+type CanaryRecord struct {
+	Word      string
+	IP        string
+	UserAgent string
+	Issued    time.Time
+	Path      string
+	// Paths lists the most recent pages the canary was served on,
+	// each prefixed with its unix timestamp.
+	Paths []string
+}
+
+// Canary returns the canary word of the IP's current session, issuing a
+// new one when the session has none yet. Canaries are invented words that
+// appear nowhere else, so finding one in a model's output ties it back to
+// the crawler that fetched it. Records never expire; the per-IP marker
+// lives as long as the session.
+func (c *Client) Canary(r *http.Request) (string, error) {
+	ip := r.Header.Get("CF-Connecting-IP")
+	sessionKey := fmt.Sprintf("trap:canary:%s", ip)
+	now := time.Now().Unix()
+
+	word, err := c.Rdb.Get(c.Ctx, sessionKey).Result()
+	switch {
+	case err == nil:
+	case errors.Is(err, redis.Nil):
+		word, err = c.issueCanary(r, ip, now)
+		if err != nil {
+			return "", err
+		}
+	default:
+		slog.Error("failed to get canary", "ip", ip, "error", err)
+		return "", fmt.Errorf("get canary: %w", err)
+	}
+
+	pathsKey := fmt.Sprintf("canary:%s:paths", word)
+	pipe := c.Rdb.TxPipeline()
+	pipe.SetEx(c.Ctx, sessionKey, word, ttlSet)
+	pipe.LPush(c.Ctx, pathsKey, strconv.FormatInt(now, 10)+" "+r.URL.RequestURI())
+	pipe.LTrim(c.Ctx, pathsKey, 0, maxCanaryPaths-1)
+	if _, err := pipe.Exec(c.Ctx); err != nil {
+		slog.Error("failed to refresh canary", "ip", ip, "error", err)
+		return "", fmt.Errorf("refresh canary: %w", err)
+	}
+	return word, nil
+}
+
+// issueCanary invents a word no earlier session has received and records
+// who it was issued to.
+func (c *Client) issueCanary(r *http.Request, ip string, now int64) (string, error) {
+	const attempts = 8
+	for range attempts {
+		word := inventWord()
+		key := fmt.Sprintf("canary:%s", word)
+		claimed, err := c.Rdb.HSetNX(c.Ctx, key, "ip", ip).Result()
+		if err != nil {
+			slog.Error("failed to claim canary", "ip", ip, "error", err)
+			return "", fmt.Errorf("claim canary: %w", err)
+		}
+		if !claimed {
+			continue
+		}
+		err = c.Rdb.HSet(c.Ctx, key,
+			"user_agent", r.UserAgent(),
+			"issued", strconv.FormatInt(now, 10),
+			"path", r.URL.RequestURI(),
+		).Err()
+		if err != nil {
+			slog.Error("failed to record canary", "ip", ip, "error", err)
+			return "", fmt.Errorf("record canary: %w", err)
+		}
+		slog.Info("canary issued", "ip", ip, "canary", word)
+		return word, nil
+	}
+	return "", fmt.Errorf("no unused canary after %d attempts", attempts)
+}
+
+// LookupCanary returns the records of every canary found in phrase,
+// which may be a single word or a passage quoted from a model.
+func (c *Client) LookupCanary(phrase string) ([]CanaryRecord, error) {
+	var records []CanaryRecord
+	seen := make(map[string]bool)
+	for _, field := range strings.Fields(phrase) {
+		word := strings.ToLower(strings.TrimFunc(field, func(r rune) bool {
+			return !unicode.IsLetter(r)
+		}))
+		if word == "" || seen[word] {
+			continue
+		}
+		seen[word] = true
+
+		fields, err := c.Rdb.HGetAll(c.Ctx, fmt.Sprintf("canary:%s", word)).Result()
+		if err != nil {
+			return nil, fmt.Errorf("lookup canary: %w", err)
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		paths, err := c.Rdb.LRange(c.Ctx, fmt.Sprintf("canary:%s:paths", word), 0, -1).Result()
+		if err != nil {
+			return nil, fmt.Errorf("lookup canary paths: %w", err)
+		}
+		issued, _ := strconv.ParseInt(fields["issued"], 10, 64)
+		records = append(records, CanaryRecord{
+			Word:      word,
+			IP:        fields["ip"],
+			UserAgent: fields["user_agent"],
+			Issued:    time.Unix(issued, 0),
+			Path:      fields["path"],
+			Paths:     paths,
+		})
+	}
+	if len(records) == 0 {
+		return nil, ErrUnknownCanary
+	}
+	return records, nil
+}
+
+var (
+	canaryOnsets = []string{
+		"b", "d", "f", "g", "k", "l", "m", "n", "p", "r", "s", "t", "v", "z",
+		"br", "dr", "gr", "kl", "pr", "st", "tr", "th", "sh", "qu",
+	}
+	canaryVowels = []string{"a", "e", "i", "o", "u", "ae", "ei", "ou"}
+	canaryCodas  = []string{"l", "n", "r", "s", "th", "x", "nd", "rk"}
+)
+
+// inventWord builds a pronounceable nonsense word of three open
+// syllables and a closing consonant, so it reads like a real term.
+func inventWord() string {
+	var b strings.Builder
+	for range 3 {
+		b.WriteString(canaryOnsets[rand.IntN(len(canaryOnsets))]) //nolint:gosec // uniqueness is checked in Redis
+		b.WriteString(canaryVowels[rand.IntN(len(canaryVowels))]) //nolint:gosec
+	}
+	b.WriteString(canaryCodas[rand.IntN(len(canaryCodas))]) //nolint:gosec
+	return b.String()
+}
@@ -0,0 +1,44 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RunCanary executes an "erebus canary" subcommand with the given arguments.
+// "lookup <phrase>" reports which sessions received the canaries in phrase.
+func RunCanary(args []string, stdout io.Writer) error {
+	if len(args) < 2 || args[0] != "lookup" {
+		return errors.New("usage: erebus canary lookup <phrase>")
+	}
+
+	rc, err := New()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = rc.Close()
+	}()
+
+	records, err := rc.LookupCanary(strings.Join(args[1:], " "))
+	if err != nil {
+		return err
+	}
+	for _, rec := range records {
+		_, _ = fmt.Fprintf(stdout, "%s\n  ip:         %s\n  user agent: %s\n  issued:     %s\n  first path: %s\n",
+			rec.Word, rec.IP, rec.UserAgent, rec.Issued.UTC().Format(time.RFC3339), rec.Path)
+		_, _ = fmt.Fprintf(stdout, "  served on %d recorded pages\n", len(rec.Paths))
+		for _, entry := range rec.Paths {
+			ts, path, _ := strings.Cut(entry, " ")
+			if unix, err := strconv.ParseInt(ts, 10, 64); err == nil {
+				ts = time.Unix(unix, 0).UTC().Format(time.RFC3339)
+			}
+			_, _ = fmt.Fprintf(stdout, "    %s %s\n", ts, path)
+		}
+	}
+	return nil
+}
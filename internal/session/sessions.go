@@ -41,6 +41,7 @@ func (c *Client) SetIP(r *http.Request) error {
 	firstSeenKey := fmt.Sprintf("trap:first-seen:%s", ip)
 	lastSeenKey := fmt.Sprintf("trap:last-seen:%s", ip)
 	realIPKey := fmt.Sprintf("real-ip:%s", ip)
+	generatorKey := fmt.Sprintf("trap:generator:%s", ip)
 
 	now := time.Now().Unix()
 	nowStr := strconv.FormatInt(now, 10)
@@ -69,6 +70,8 @@ func (c *Client) SetIP(r *http.Request) error {
 	ctx := c.Ctx
 	firstSeenStr, firstErr := c.Rdb.Get(ctx, firstSeenKey).Result()
 	lastSeenStr, lastErr := c.Rdb.Get(ctx, lastSeenKey).Result()
+	// The generator is informational only, a missing value is logged as empty.
+	generator, _ := c.Rdb.Get(ctx, generatorKey).Result()
 
 	// Log session end if both timestamps exist
 	switch {
@@ -82,6 +85,7 @@ func (c *Client) SetIP(r *http.Request) error {
 				"trapped_seconds", trappedSeconds,
 				"first_seen", firstSeen,
 				"last_seen", lastSeen,
+				"generator", generator,
 			)
 		}
 	case !errors.Is(firstErr, redis.Nil) && firstErr != nil:
@@ -105,6 +109,19 @@ func (c *Client) SetIP(r *http.Request) error {
 	return nil
 }
 
+// SetGenerator records which text generator served the IP's latest page,
+// so trapped durations can be compared between generator backends.
+func (c *Client) SetGenerator(r *http.Request, name string) error {
+	ip := r.Header.Get("CF-Connecting-IP")
+	key := fmt.Sprintf("trap:generator:%s", ip)
+
+	if err := c.Set(key, name, ttlHistory); err != nil {
+		slog.Error("failed to store generator", "ip", ip, "error", err)
+		return fmt.Errorf("store generator: %w", err)
+	}
+	return nil
+}
+
 // GetKey returns a key from redis as a string.
 func (c *Client) GetKey(key string) (string, error) {
 	if key == "" {
@@ -3,12 +3,19 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
+	"Erebus/internal/admin"
+	"Erebus/internal/bable"
+	"Erebus/internal/corpus"
+	"Erebus/internal/erebusconfig"
 	"Erebus/internal/pages"
 	"Erebus/internal/session"
 	"Erebus/internal/utils"
@@ -18,6 +25,14 @@ import (
 func main() {
 	slog.SetDefault(slog.New(slogcolor.NewHandler(os.Stderr, slogcolor.DefaultOptions)))
 
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "erebus: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	rc, err := session.New()
 	if err != nil {
 		slog.Error("redis connection failed", "err", err)
@@ -25,8 +40,33 @@ func main() {
 	}
 	slog.Info("redis connected")
 
+	// Build every chain the page generators use before accepting traffic.
+	start := time.Now()
+	bable.Warm()
+	slog.Info("markov chains built", "took", time.Since(start))
+
+	// Rebuild the chains in the background when the corpora change,
+	// either on SIGHUP or when the watcher notices a modified file.
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			slog.Info("SIGHUP received, reloading corpora")
+			if err := bable.Reload(); err != nil {
+				slog.Error("corpus reload failed", "err", err)
+			}
+		}
+	}()
+	if interval := erebusconfig.Conf.CorpusWatchInterval; interval > 0 {
+		go bable.Watch(context.Background(), time.Duration(interval*float64(time.Second)))
+	}
+
 	http.HandleFunc("/robots.txt", pages.RobotsHandler)
 	http.HandleFunc("/sitemap.xml", pages.SitemapHandler)
+	http.HandleFunc("/sitemaps/", pages.ChildSitemapHandler)
+	if admin.Enabled() {
+		http.HandleFunc(admin.StatsPath, admin.StatsHandler)
+	}
 	http.HandleFunc("/", pages.MakeGenerateHandler(rc))
 
 	server := &http.Server{
@@ -43,3 +83,15 @@ func main() {
 		slog.Error("server error", "err", err)
 	}
 }
+
+// runCommand runs an offline subcommand instead of the server.
+func runCommand(args []string) error {
+	switch args[0] {
+	case "corpus":
+		return corpus.Run(args[1:], os.Stdout, os.Stderr)
+	case "canary":
+		return session.RunCanary(args[1:], os.Stdout)
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}